@@ -0,0 +1,130 @@
+package gloader
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBatchTargetBytes        = 4 * 1024 * 1024 // 4 MB
+	DefaultBatchTargetLatency      = time.Second
+	DefaultMinAdaptiveRowsPerBatch = 1
+	DefaultMaxAdaptiveRowsPerBatch = 100000
+	// DefaultAdaptiveWriteRetries is the number of times a failed write is retried with a smaller batch size.
+	DefaultAdaptiveWriteRetries = 3
+)
+
+// BatchSizeObserver is an interface that can be implemented to observe the adaptive batch size.
+type BatchSizeObserver interface {
+	// BatchSizeChanged is called when the number of rows per batch changes.
+	BatchSizeChanged(rows uint64)
+}
+
+// AdaptiveBatchSize tunes the number of rows per batch at runtime.
+// It targets a size in bytes and a latency per batch, based on the observed batches,
+// and backs off when a batch fails.
+// It is safe for concurrent use by multiple workers.
+type AdaptiveBatchSize struct {
+	current       uint64
+	min           uint64
+	max           uint64
+	targetBytes   uint64
+	targetLatency time.Duration
+	observe       BatchSizeObserver
+	mu            *sync.Mutex
+}
+
+// NewAdaptiveBatchSize returns a new adaptive batch size that starts with the given number of rows per batch.
+func NewAdaptiveBatchSize(initial, targetBytes uint64, targetLatency time.Duration) *AdaptiveBatchSize {
+	a := &AdaptiveBatchSize{
+		min:           DefaultMinAdaptiveRowsPerBatch,
+		max:           DefaultMaxAdaptiveRowsPerBatch,
+		targetBytes:   targetBytes,
+		targetLatency: targetLatency,
+		mu:            &sync.Mutex{},
+	}
+	a.current = a.clamp(initial)
+	return a
+}
+
+// WithObserver sets the observer of the batch size.
+func (a *AdaptiveBatchSize) WithObserver(observer BatchSizeObserver) *AdaptiveBatchSize {
+	a.observe = observer
+	a.observe.BatchSizeChanged(a.Current())
+	return a
+}
+
+// SetLimits sets the minimum and maximum number of rows per batch.
+func (a *AdaptiveBatchSize) SetLimits(minRows, maxRows uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.min = minRows
+	a.max = maxRows
+	a.set(a.current)
+}
+
+// Current returns the current number of rows per batch.
+func (a *AdaptiveBatchSize) Current() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.current
+}
+
+// Observe adjusts the number of rows per batch according to a successful batch,
+// with the given number of rows, size in bytes, and latency.
+func (a *AdaptiveBatchSize) Observe(rows, size uint64, latency time.Duration) {
+	if rows == 0 {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	next := a.max
+	if a.targetBytes > 0 && size > 0 {
+		if bySize := a.targetBytes * rows / size; bySize < next {
+			next = bySize
+		}
+	}
+	if a.targetLatency > 0 && latency > 0 {
+		if byLatency := uint64(float64(rows) * float64(a.targetLatency) / float64(latency)); byLatency < next {
+			next = byLatency
+		}
+	}
+
+	// move halfway to the target, so a single outlier batch doesn't swing the batch size.
+	if next > a.current {
+		a.set(a.current + (next-a.current+1)/2)
+	} else {
+		a.set(a.current - (a.current-next)/2)
+	}
+}
+
+// Backoff halves the number of rows per batch. It is called when a batch fails or times out.
+func (a *AdaptiveBatchSize) Backoff() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.set(a.current / 2)
+}
+
+func (a *AdaptiveBatchSize) set(rows uint64) {
+	rows = a.clamp(rows)
+	if rows == a.current {
+		return
+	}
+
+	a.current = rows
+	if a.observe != nil {
+		a.observe.BatchSizeChanged(rows)
+	}
+}
+
+func (a *AdaptiveBatchSize) clamp(rows uint64) uint64 {
+	if rows < a.min {
+		return a.min
+	}
+	if rows > a.max {
+		return a.max
+	}
+	return rows
+}
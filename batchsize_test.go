@@ -0,0 +1,128 @@
+package gloader
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/driver"
+)
+
+type batchSizeRecorder struct {
+	sizes []uint64
+}
+
+func (r *batchSizeRecorder) BatchSizeChanged(rows uint64) {
+	r.sizes = append(r.sizes, rows)
+}
+
+func TestAdaptiveBatchSizeObserve(t *testing.T) {
+	tests := []struct {
+		name          string
+		targetBytes   uint64
+		targetLatency time.Duration
+		rows, size    uint64
+		latency       time.Duration
+		want          uint64
+	}{
+		// 100 bytes per row targets 10 rows, and it moves halfway there.
+		{name: "shrinks by size", targetBytes: 1000, rows: 100, size: 10000, want: 55},
+		// 1 byte per row targets 1000 rows.
+		{name: "grows by size", targetBytes: 1000, rows: 100, size: 100, want: 550},
+		// 4 times the target latency targets 25 rows.
+		{name: "shrinks by latency", targetLatency: time.Second, rows: 100, latency: 4 * time.Second, want: 63},
+		// the smaller of the targets wins.
+		{name: "both targets", targetBytes: 1000, targetLatency: time.Second, rows: 100, size: 100, latency: 4 * time.Second, want: 63},
+		{name: "no targets grows to max", rows: 100, want: 100 + (DefaultMaxAdaptiveRowsPerBatch-100+1)/2},
+		{name: "empty batch", targetBytes: 1000, size: 10000, want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAdaptiveBatchSize(100, tt.targetBytes, tt.targetLatency)
+			a.Observe(tt.rows, tt.size, tt.latency)
+			if got := a.Current(); got != tt.want {
+				t.Errorf("Current() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdaptiveBatchSizeConverges(t *testing.T) {
+	a := NewAdaptiveBatchSize(10, 1000, 0)
+	for i := 0; i < 20; i++ {
+		rows := a.Current()
+		a.Observe(rows, rows*10, time.Millisecond)
+	}
+	if got := a.Current(); got < 95 || got > 100 {
+		t.Errorf("Current() = %d, want about 100", got)
+	}
+}
+
+func TestAdaptiveBatchSizeBackoffAndLimits(t *testing.T) {
+	recorder := &batchSizeRecorder{}
+	a := NewAdaptiveBatchSize(10, 0, 0).WithObserver(recorder)
+	a.SetLimits(3, 50)
+
+	a.Backoff()
+	a.Backoff()
+	// it can't go below the minimum, so the last backoff doesn't change it.
+	a.Backoff()
+	a.Observe(10, 0, 0)
+
+	if got, want := recorder.sizes, []uint64{10, 5, 3, 27}; !reflect.DeepEqual(got, want) {
+		t.Errorf("observed batch sizes = %v, want %v", got, want)
+	}
+
+	a.SetLimits(1, 20)
+	if got := a.Current(); got != 20 {
+		t.Errorf("Current() after lowering the maximum = %d, want 20", got)
+	}
+	if got := NewAdaptiveBatchSize(0, 0, 0).Current(); got != DefaultMinAdaptiveRowsPerBatch {
+		t.Errorf("Current() of a zero initial batch size = %d, want %d", got, DefaultMinAdaptiveRowsPerBatch)
+	}
+}
+
+func TestWriterRetriesFailedWritesInSmallerBatches(t *testing.T) {
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 0)
+	errTooLarge := errors.New("batch too large")
+	dest.writeErr = func(_ string, batch *data.Batch) error {
+		if batch.GetLength() > 5 {
+			return errTooLarge
+		}
+		return nil
+	}
+
+	buffer := data.NewBuffer(context.Background())
+	batch := data.NewDataBatch()
+	for i := int64(0); i < 20; i++ {
+		batch.Add(newIDSet(t, i))
+	}
+	if err := buffer.WriteBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+	if err := buffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := driver.GetDriver("memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := NewWriter(context.Background(), "users", buffer, driver.NewConnectionPool(driver.NewConnector(d, dest.name)))
+	writer.SetWorkers(1)
+	batchSize := NewAdaptiveBatchSize(20, 0, 0)
+	writer.SetAdaptiveBatchSize(batchSize)
+	if err := writer.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 20 rows fail, then chunks of 10 fail, then chunks of 5 are written.
+	if got, want := dest.writtenBatches("users"), []uint64{5, 5, 5, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("written batches = %v, want %v", got, want)
+	}
+	wantIDs(t, dest.ids("users"), 20)
+}
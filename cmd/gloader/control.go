@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	g "github.com/mohammadv184/gloader"
+	"github.com/mohammadv184/gloader/pkg/log"
+)
+
+// newControlServer returns an HTTP server to control a running migration.
+//
+//	GET  /rate-limit?kind=read-rows[&table=orders]            returns the current rate limit.
+//	POST /rate-limit?kind=read-rows&rate=1000[&table=orders]  changes the rate limit, 0 means unlimited.
+//	GET  /log-level                                            returns the current log level.
+//	POST /log-level?level=debug                                changes the log level.
+func newControlServer(addr string, gloader *g.GLoader, logger *log.Logger) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rate-limit", func(w http.ResponseWriter, r *http.Request) {
+		kind, err := g.GetRateLimitKindFromString(r.URL.Query().Get("kind"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		table := r.URL.Query().Get("table")
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			rate, err := strconv.ParseFloat(r.URL.Query().Get("rate"), 64)
+			if err != nil || rate < 0 {
+				http.Error(w, "invalid rate", http.StatusBadRequest)
+				return
+			}
+
+			if table == "" {
+				gloader.SetRateLimit(kind, rate)
+			} else {
+				gloader.SetDataCollectionRateLimit(table, kind, rate)
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		fmt.Fprintf(w, "%s %s %g\n", table, kind, gloader.GetRateLimit(table, kind))
+	})
+	mux.HandleFunc("/log-level", func(w http.ResponseWriter, r *http.Request) {
+		h, ok := logger.Handler().(*log.Handler)
+		if !ok {
+			http.Error(w, "the log level can't be changed", http.StatusNotImplemented)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			level, err := log.ParseLevel(r.URL.Query().Get("level"))
+			if err != nil {
+				http.Error(w, "invalid level", http.StatusBadRequest)
+				return
+			}
+			h.SetLevel(level)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		fmt.Fprintln(w, h.Level())
+	})
+
+	return &http.Server{Addr: addr, Handler: mux}
+}
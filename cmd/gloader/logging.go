@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mohammadv184/gloader/pkg/log"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// newLogger returns the logger of the CLI with the given level and format.
+// The logs are written to the file when it's given, otherwise the text logs below the error level
+// are written to stdout and the others to stderr, and the json logs are written to stderr.
+// All the logs are written to stderr when stdout is used by the json progress events.
+// The returned function closes the log file.
+func newLogger(level, format, file string, stdoutInUse bool) (*log.Logger, func(), error) {
+	l, err := log.ParseLevel(level)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown log level: %s", level)
+	}
+
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	if stdoutInUse {
+		stdout = os.Stderr
+	}
+	closeFunc := func() {}
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+		stdout, stderr = f, f
+		closeFunc = func() { _ = f.Close() }
+	}
+
+	switch strings.ToLower(format) {
+	case logFormatText:
+		return log.NewLogger(log.NewHandler(stdout, stderr, "GLoader").SetLevel(l)), closeFunc, nil
+	case logFormatJSON:
+		h := log.NewHandler(stderr, stderr, "GLoader").SetLevel(l).SetFormat(log.FormatJSON)
+		return log.NewLogger(h), closeFunc, nil
+	default:
+		closeFunc()
+		return nil, nil, fmt.Errorf("unknown log format: %s", format)
+	}
+}
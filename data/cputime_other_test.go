@@ -0,0 +1,10 @@
+//go:build !unix
+
+package data_test
+
+import "time"
+
+// processCPUTime isn't measured on this platform, so the cpu-ns/op metrics of the benchmarks are 0.
+func processCPUTime() time.Duration {
+	return 0
+}
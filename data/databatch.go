@@ -38,6 +38,7 @@ func (b *Batch) Pop() *Set {
 	}
 
 	set := b.Get(0)
+	(*b)[0] = nil // release the reference, so it can be garbage collected.
 	*b = (*b)[1:]
 	return set
 }
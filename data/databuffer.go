@@ -12,13 +12,23 @@ const (
 	DefaultMaxBufferLength = 500000
 )
 
+// minBufferCapacity is the initial capacity of the buffer ring.
+const minBufferCapacity = 64
+
 // Buffer is a thread-safe data buffer. It is used to buffer data sets before they are written to the database.
+// The data sets are kept in a ring that grows on demand, and readers and writers are blocked
+// on condition variables instead of polling the buffer state.
 type Buffer struct {
-	data *Batch
+	ring   []*Set
+	head   int
+	length uint64
+	size   uint64
 
-	locker *sync.RWMutex
+	locker   *sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
 
-	close chan any
+	closed bool
 
 	ctx context.Context
 
@@ -26,6 +36,8 @@ type Buffer struct {
 
 	maxLength uint64
 
+	spill *Spill
+
 	observe BufferObserver
 }
 
@@ -40,23 +52,43 @@ type BufferObserver interface {
 	Read(n int)
 }
 
+// SpillObserver is an optional interface of the BufferObserver to observe the data sets spilled to disk.
+type SpillObserver interface {
+	// Spilled is called when data sets are spilled to disk, with their encoded size in bytes.
+	Spilled(bytes uint64)
+	// SpillSizeChanged is called when the size of the spilled data sets on disk changes.
+	SpillSizeChanged(s uint64)
+}
+
 // NewBuffer creates a new buffer with the given maximum size in bytes.
 // If no size is given, the DefaultMaxBufferLength is used.
 // SizeChanged: the maximum size of the buffer in bytes. And cannot be 0.
+// The buffer is closed when the given context is done.
 func NewBuffer(ctx context.Context, size ...uint64) *Buffer {
 	var bSize uint64 = DefaultMaxBufferSize
 	if len(size) > 0 && size[0] > 0 {
 		bSize = size[0]
 	}
 
-	return &Buffer{
-		data:      NewDataBatch(),
-		maxSize:   bSize,
-		close:     make(chan any),
+	return newBuffer(ctx, bSize, DefaultMaxBufferLength)
+}
+
+func newBuffer(ctx context.Context, maxSize, maxLength uint64) *Buffer {
+	locker := &sync.Mutex{}
+	b := &Buffer{
+		ring:      make([]*Set, minBufferCapacity),
+		maxSize:   maxSize,
+		maxLength: maxLength,
 		ctx:       ctx,
-		locker:    &sync.RWMutex{},
-		maxLength: DefaultMaxBufferLength,
+		locker:    locker,
+		notEmpty:  sync.NewCond(locker),
+		notFull:   sync.NewCond(locker),
 	}
+
+	context.AfterFunc(ctx, func() {
+		_ = b.Close()
+	})
+	return b
 }
 
 func (b *Buffer) WithObserver(observer BufferObserver) *Buffer {
@@ -64,79 +96,249 @@ func (b *Buffer) WithObserver(observer BufferObserver) *Buffer {
 	return b
 }
 
+// WithSpill makes the buffer spill the overflowing data sets to the given spill instead of blocking the writers.
+// Writers are blocked only when the spill disk quota is exhausted.
+// The data sets are read back in the same order they were written.
+// The spill is not closed by the buffer.
+func (b *Buffer) WithSpill(spill *Spill) *Buffer {
+	b.spill = spill
+	return b
+}
+
 // Write writes the given data sets to the buffer.
 // If the buffer exceeds the maximum conditions, it will be blocked until the buffer conditions are met.
 // If the buffer is closed, it will return an error.
 func (b *Buffer) Write(data ...*Set) error {
-	b.checkConditions()
-	if b.IsClosed() {
-		return ErrBufferIsClosed
-	}
 	b.locker.Lock()
 	defer b.locker.Unlock()
-	b.data.Add(data...)
 
-	b.observeChanges(b.data.GetLength(), b.data.GetSize())
-	b.observe.Write(len(data))
-	return nil
-}
-
-// Read pops the first data set from the buffer. If the buffer is empty, it will be blocked until the next data set is written to the buffer.
-// If the buffer is closed, and all data sets have been read, it will return ErrBufferIsClosed.
-func (b *Buffer) Read() (*Set, error) {
+	n := countSets(data)
+	var encoded []byte
 	for {
-		data, err := b.popDataSet()
+		if b.closed {
+			return ErrBufferIsClosed
+		}
+		if n == 0 {
+			// nothing is written, so an empty segment isn't spilled and the readers aren't woken up.
+			return nil
+		}
+
+		// once a data set is spilled, the next ones are spilled too until the spill is drained,
+		// so the data sets are read in the same order they were written.
+		if b.spill != nil && (b.isFull() || !b.spill.IsEmpty()) {
+			if encoded == nil {
+				var err error
+				if encoded, err = b.spill.Encode(data); err != nil {
+					return err
+				}
+			}
 
-		if data != nil || err != nil {
-			if data != nil {
-				b.observe.Read(1)
+			spilled, err := b.spillData(encoded, n)
+			if err != nil {
+				return err
 			}
+			if spilled {
+				return nil
+			}
+		} else if !b.isFull() {
+			break
+		}
+		b.notFull.Wait()
+	}
 
-			return data, err
+	for _, set := range data {
+		if set != nil {
+			b.push(set)
 		}
 	}
+
+	b.observeChanges(b.length, b.size)
+	if b.observe != nil {
+		b.observe.Write(n)
+	}
+	b.notEmpty.Broadcast()
+	return nil
 }
 
-// popDataSet pops the first data set from the buffer. If the buffer is empty, it will be waited.
-// If the buffer is closed, and all data sets have been read, it will return an error.
-func (b *Buffer) popDataSet() (*Set, error) {
-	for {
-		if b.IsClosed() && b.IsEmpty() {
-			// fmt.Println("buffer is closed")
-			return nil, ErrBufferIsClosed
+// spillData pushes the given n encoded data sets to the spill.
+// It returns false if the spill disk quota is exhausted.
+// The caller must hold the lock.
+func (b *Buffer) spillData(encoded []byte, n int) (bool, error) {
+	spilled, err := b.spill.Push(encoded, uint64(n))
+	if err != nil || !spilled {
+		return false, err
+	}
+
+	if b.observe != nil {
+		b.observe.Write(n)
+		if o, ok := b.observe.(SpillObserver); ok {
+			o.Spilled(uint64(len(encoded)))
+			o.SpillSizeChanged(b.spill.GetSize())
 		}
-		if b.IsEmpty() {
-			continue
+	}
+	b.notEmpty.Broadcast()
+	return true, nil
+}
+
+// unspill moves the oldest spilled data sets to the memory.
+// The caller must hold the lock.
+func (b *Buffer) unspill() error {
+	sets, err := b.spill.Pop()
+	if err != nil {
+		return err
+	}
+
+	for _, set := range sets {
+		b.push(set)
+	}
+
+	if o, ok := b.observe.(SpillObserver); ok {
+		o.SpillSizeChanged(b.spill.GetSize())
+	}
+	// the spill disk quota is released, so the blocked writers can spill again.
+	b.notFull.Broadcast()
+	return nil
+}
+
+// waitForData blocks until the buffer has a data set in memory, or it is closed and drained.
+// It returns ErrBufferIsClosed if the buffer is closed and drained.
+// The caller must hold the lock.
+func (b *Buffer) waitForData() error {
+	for !b.closed && b.length == 0 && b.isSpillEmpty() {
+		b.notEmpty.Wait()
+	}
+
+	if b.length == 0 && !b.isSpillEmpty() {
+		if err := b.unspill(); err != nil {
+			return err
 		}
-		break
 	}
+
+	if b.length == 0 {
+		return ErrBufferIsClosed
+	}
+	return nil
+}
+
+// Read pops the first data set from the buffer. If the buffer is empty, it will be blocked until the next data set is written to the buffer.
+// If the buffer is closed, and all data sets have been read, it will return ErrBufferIsClosed.
+func (b *Buffer) Read() (*Set, error) {
 	b.locker.Lock()
 	defer b.locker.Unlock()
-	dSet := b.data.Pop()
-	b.observeChanges(b.data.GetLength(), b.data.GetSize())
+
+	if err := b.waitForData(); err != nil {
+		return nil, err
+	}
+
+	dSet := b.pop()
+
+	b.observeChanges(b.length, b.size)
+	if b.observe != nil {
+		b.observe.Read(1)
+	}
+	b.notFull.Broadcast()
 	return dSet, nil
 }
 
-// Clear clears the buffer.
+// WriteBatch writes all the data sets of the given batch to the buffer at once.
+// It blocks and fails the same way as Write.
+func (b *Buffer) WriteBatch(batch *Batch) error {
+	return b.Write(*batch...)
+}
+
+// ReadBatch pops up to limit data sets from the buffer at once.
+// If the buffer is empty, it will be blocked until the next data set is written to the buffer,
+// then it returns the data sets that are available without waiting for the batch to be full.
+// If the buffer is closed, and all data sets have been read, it will return ErrBufferIsClosed.
+// A zero limit pops all the available data sets.
+func (b *Buffer) ReadBatch(limit uint64) (*Batch, error) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+
+	if err := b.waitForData(); err != nil {
+		return nil, err
+	}
+
+	n := b.length
+	if limit > 0 && limit < n {
+		n = limit
+	}
+
+	batch := make(Batch, n)
+	for i := range batch {
+		batch[i] = b.pop()
+	}
+
+	b.observeChanges(b.length, b.size)
+	if b.observe != nil {
+		b.observe.Read(len(batch))
+	}
+	b.notFull.Broadcast()
+	return &batch, nil
+}
+
+// push appends the given data set to the end of the ring, growing the ring if it is full.
+// The caller must hold the lock.
+func (b *Buffer) push(set *Set) {
+	if b.length == uint64(len(b.ring)) {
+		ring := make([]*Set, len(b.ring)*2)
+		n := copy(ring, b.ring[b.head:])
+		copy(ring[n:], b.ring[:b.head])
+		b.ring = ring
+		b.head = 0
+	}
+
+	b.ring[(b.head+int(b.length))%len(b.ring)] = set
+	b.length++
+	b.size += set.GetSize()
+}
+
+// pop removes the first data set of the ring and returns it.
+// The caller must hold the lock, and the ring must not be empty.
+func (b *Buffer) pop() *Set {
+	set := b.ring[b.head]
+	b.ring[b.head] = nil // release the reference, so it can be garbage collected.
+	b.head = (b.head + 1) % len(b.ring)
+	b.length--
+	b.size -= set.GetSize()
+
+	if b.length == 0 {
+		b.head = 0
+		b.size = 0
+	}
+	return set
+}
+
+// Clear clears the buffer, including the spilled data sets.
 func (b *Buffer) Clear() {
 	b.locker.Lock()
 	defer b.locker.Unlock()
-	b.data = NewDataBatch()
-	b.observeChanges(b.data.GetLength(), b.data.GetSize())
+	if b.spill != nil {
+		_ = b.spill.Clear()
+	}
+	b.ring = make([]*Set, minBufferCapacity)
+	b.head = 0
+	b.length = 0
+	b.size = 0
+	b.observeChanges(b.length, b.size)
+	b.notFull.Broadcast()
 }
 
 // GetSize returns the current size of the buffer in bytes.
 func (b *Buffer) GetSize() uint64 {
-	b.locker.RLock()
-	defer b.locker.RUnlock()
-	return b.data.GetSize()
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	return b.size
 }
 
-// GetLength returns the current length of the buffer.
+// GetLength returns the current length of the buffer, including the spilled data sets.
 func (b *Buffer) GetLength() uint64 {
-	b.locker.RLock()
-	defer b.locker.RUnlock()
-	return b.data.GetLength()
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if b.spill != nil {
+		return b.length + b.spill.GetLength()
+	}
+	return b.length
 }
 
 // Size sets the maximum size of the buffer in bytes.
@@ -145,6 +347,7 @@ func (b *Buffer) Size(size uint64) {
 	b.locker.Lock()
 	defer b.locker.Unlock()
 	b.maxSize = size
+	b.notFull.Broadcast()
 }
 
 // Length sets the maximum length of the buffer.
@@ -153,95 +356,73 @@ func (b *Buffer) Length(length uint64) {
 	b.locker.Lock()
 	defer b.locker.Unlock()
 	b.maxLength = length
+	b.notFull.Broadcast()
 }
 
 // IsEmpty returns true if the buffer is empty.
 func (b *Buffer) IsEmpty() bool {
-	return b.data.GetLength() == 0
+	return b.GetLength() == 0
 }
 
 // Clone returns a copy of the buffer.
 // The copy will not be affected by the original buffer.
 // And Clone always returns a not closed buffer.
+// The spilled data sets are not copied, and the copy doesn't spill.
 func (b *Buffer) Clone() *Buffer {
-	b.locker.RLock()
-	defer b.locker.RUnlock()
-	return &Buffer{
-		data:      b.data.Clone(),
-		locker:    &sync.RWMutex{},
-		maxSize:   b.maxSize,
-		close:     make(chan any),
-		maxLength: b.maxLength,
-		ctx:       b.ctx,
-		observe:   b.observe,
+	b.locker.Lock()
+	defer b.locker.Unlock()
+
+	clone := newBuffer(b.ctx, b.maxSize, b.maxLength)
+	clone.observe = b.observe
+	for i := uint64(0); i < b.length; i++ {
+		clone.push(b.ring[(b.head+int(i))%len(b.ring)].Clone())
 	}
+	return clone
 }
 
 // IsClosed returns true if the buffer is closed.
 func (b *Buffer) IsClosed() bool {
-	select {
-	case <-b.close:
-		return true
-	default:
-		return false
-	}
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	return b.closed
 }
 
 // Close will close the buffer.
 // Closed buffer will not accept new data sets.
 // If the buffer is already closed, it will return an error.
 func (b *Buffer) Close() error {
-	if b.IsClosed() {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if b.closed {
 		return ErrBufferAlreadyIsClosed
 	}
-	close(b.close)
+	b.closed = true
+
+	// wake up all the blocked readers and writers, so they can notice the buffer is closed.
+	b.notEmpty.Broadcast()
+	b.notFull.Broadcast()
 	return nil
 }
 
-// checkMaxSize checks the maximum size and length of the buffer.
-// If the buffer exceeds the maximum conditions, it will be blocked until the buffer conditions are met.
-func (b *Buffer) checkMaxSize() {
-	checkCh := make(chan any)
-	go func() {
-		for b.maxSize < b.GetSize() || b.maxLength < b.GetLength() {
-			if b.IsClosed() {
-				checkCh <- 1
-				return
-			}
-			continue
-		}
-		checkCh <- 1
-	}()
-
-	select {
-	case <-b.ctx.Done():
-		err := b.Close()
-		if err != nil {
-			// TODO: logging system
-			// log.Println(err)
+// countSets returns the number of the given data sets that aren't nil.
+func countSets(data []*Set) int {
+	var n int
+	for _, set := range data {
+		if set != nil {
+			n++
 		}
-		return
-	case <-checkCh:
-		close(checkCh)
-		return
 	}
+	return n
 }
 
-func (b *Buffer) checkContext() {
-	select {
-	case <-b.ctx.Done():
-		err := b.Close()
-		if err != nil {
-			// TODO: logging system
-			// log.Println(err)
-		}
-	default:
-	}
+func (b *Buffer) isSpillEmpty() bool {
+	return b.spill == nil || b.spill.IsEmpty()
 }
 
-func (b *Buffer) checkConditions() {
-	b.checkContext()
-	b.checkMaxSize()
+// isFull reports whether the buffer exceeds the maximum size or length.
+// The caller must hold the lock.
+func (b *Buffer) isFull() bool {
+	return b.maxSize < b.size || b.maxLength < b.length
 }
 
 func (b *Buffer) observeChanges(l uint64, s uint64) {
@@ -0,0 +1,313 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/data/types"
+)
+
+// benchBuffer is the part of the buffer API that the benchmarks use,
+// so the ring buffer can be compared with the busy-waiting legacyBuffer it replaced.
+type benchBuffer interface {
+	Write(data ...*data.Set) error
+	Read() (*data.Set, error)
+	WriteBatch(batch *data.Batch) error
+	ReadBatch(limit uint64) (*data.Batch, error)
+	Close() error
+}
+
+var benchBuffers = []struct {
+	name string
+	new  func(ctx context.Context) benchBuffer
+}{
+	{"ring", func(ctx context.Context) benchBuffer { return data.NewBuffer(ctx) }},
+	{"legacy", func(ctx context.Context) benchBuffer { return newLegacyBuffer(ctx) }},
+}
+
+func newBenchSet(i int) *data.Set {
+	id := &types.IntegerType{}
+	id.Init(id)
+	_ = id.Parse(i)
+	name := &types.StringType{}
+	name.Init(name)
+	_ = name.Parse(fmt.Sprint("name-", i))
+
+	set := data.NewDataSet()
+	set.Add(data.NewData("id", id))
+	set.Add(data.NewData("name", name))
+	return set
+}
+
+// runBenchBuffer moves b.N data sets from the producers to the consumers through the buffer,
+// batchSize data sets at a time, or one at a time if batchSize is 0.
+// It reports the throughput in rows/s and the CPU time of the process per data set.
+func runBenchBuffer(b *testing.B, newBuffer func(ctx context.Context) benchBuffer, producers, consumers, batchSize int) {
+	set := newBenchSet(1)
+	buf := newBuffer(context.Background())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	cpuStart := processCPUTime()
+	start := time.Now()
+
+	consumersWg := &sync.WaitGroup{}
+	for c := 0; c < consumers; c++ {
+		consumersWg.Add(1)
+		go func() {
+			defer consumersWg.Done()
+			for {
+				var err error
+				if batchSize > 0 {
+					_, err = buf.ReadBatch(uint64(batchSize))
+				} else {
+					_, err = buf.Read()
+				}
+				if errors.Is(err, data.ErrBufferIsClosed) {
+					return
+				}
+				if err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	producersWg := &sync.WaitGroup{}
+	for p := 0; p < producers; p++ {
+		n := b.N / producers
+		if p == 0 {
+			n += b.N % producers
+		}
+		producersWg.Add(1)
+		go func(n int) {
+			defer producersWg.Done()
+			for n > 0 {
+				var err error
+				if batchSize > 0 {
+					size := batchSize
+					if n < size {
+						size = n
+					}
+					batch := make(data.Batch, size)
+					for i := range batch {
+						batch[i] = set
+					}
+					err = buf.WriteBatch(&batch)
+					n -= size
+				} else {
+					err = buf.Write(set)
+					n--
+				}
+				if err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}(n)
+	}
+
+	producersWg.Wait()
+	_ = buf.Close()
+	consumersWg.Wait()
+
+	elapsed := time.Since(start)
+	cpu := processCPUTime() - cpuStart
+	b.StopTimer()
+
+	b.ReportMetric(float64(b.N)/elapsed.Seconds(), "rows/s")
+	b.ReportMetric(float64(cpu.Nanoseconds())/float64(b.N), "cpu-ns/op")
+}
+
+func BenchmarkBufferWriteRead(b *testing.B) {
+	for _, bb := range benchBuffers {
+		for _, workers := range []int{1, 4, 16} {
+			b.Run(fmt.Sprintf("%s/producers=%d/consumers=%d", bb.name, workers, workers), func(b *testing.B) {
+				runBenchBuffer(b, bb.new, workers, workers, 0)
+			})
+		}
+	}
+}
+
+func BenchmarkBufferWriteReadBatch(b *testing.B) {
+	for _, bb := range benchBuffers {
+		for _, workers := range []int{1, 4, 16} {
+			b.Run(fmt.Sprintf("%s/producers=%d/consumers=%d/batch=100", bb.name, workers, workers), func(b *testing.B) {
+				runBenchBuffer(b, bb.new, workers, workers, 100)
+			})
+		}
+	}
+}
+
+// BenchmarkBufferIdle measures the CPU time burned by consumers that wait on an empty buffer for a millisecond.
+func BenchmarkBufferIdle(b *testing.B) {
+	const consumers = 4
+	for _, bb := range benchBuffers {
+		b.Run(bb.name, func(b *testing.B) {
+			cpuStart := processCPUTime()
+			for i := 0; i < b.N; i++ {
+				buf := bb.new(context.Background())
+				wg := &sync.WaitGroup{}
+				for c := 0; c < consumers; c++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, _ = buf.Read()
+					}()
+				}
+				time.Sleep(time.Millisecond)
+				_ = buf.Close()
+				wg.Wait()
+			}
+			b.ReportMetric(float64((processCPUTime()-cpuStart).Nanoseconds())/float64(b.N), "cpu-ns/op")
+		})
+	}
+}
+
+// legacyBuffer is the buffer that busy-waited for data sets and free space, before it was reworked as a ring buffer.
+// It's kept only to compare the benchmarks. IsEmpty reads under the lock, so the benchmarks are race-free,
+// but it busy-waits the same way.
+type legacyBuffer struct {
+	data      *data.Batch
+	locker    *sync.RWMutex
+	close     chan any
+	ctx       context.Context
+	maxSize   uint64
+	maxLength uint64
+}
+
+func newLegacyBuffer(ctx context.Context) *legacyBuffer {
+	return &legacyBuffer{
+		data:      data.NewDataBatch(),
+		maxSize:   data.DefaultMaxBufferSize,
+		close:     make(chan any),
+		ctx:       ctx,
+		locker:    &sync.RWMutex{},
+		maxLength: data.DefaultMaxBufferLength,
+	}
+}
+
+func (b *legacyBuffer) Write(sets ...*data.Set) error {
+	b.checkConditions()
+	if b.IsClosed() {
+		return data.ErrBufferIsClosed
+	}
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	b.data.Add(sets...)
+	return nil
+}
+
+func (b *legacyBuffer) Read() (*data.Set, error) {
+	for {
+		set, err := b.popDataSet()
+		if set != nil || err != nil {
+			return set, err
+		}
+	}
+}
+
+// WriteBatch and ReadBatch move the data sets one at a time, like the Reader and the Writer did then.
+func (b *legacyBuffer) WriteBatch(batch *data.Batch) error {
+	for _, set := range *batch {
+		if err := b.Write(set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *legacyBuffer) ReadBatch(limit uint64) (*data.Batch, error) {
+	batch := make(data.Batch, 0, limit)
+	for uint64(len(batch)) < limit {
+		set, err := b.Read()
+		if err != nil {
+			if len(batch) > 0 {
+				return &batch, nil
+			}
+			return nil, err
+		}
+		batch = append(batch, set)
+	}
+	return &batch, nil
+}
+
+func (b *legacyBuffer) popDataSet() (*data.Set, error) {
+	for {
+		if b.IsClosed() && b.IsEmpty() {
+			return nil, data.ErrBufferIsClosed
+		}
+		if b.IsEmpty() {
+			continue
+		}
+		break
+	}
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	return b.data.Pop(), nil
+}
+
+func (b *legacyBuffer) GetSize() uint64 {
+	b.locker.RLock()
+	defer b.locker.RUnlock()
+	return b.data.GetSize()
+}
+
+func (b *legacyBuffer) GetLength() uint64 {
+	b.locker.RLock()
+	defer b.locker.RUnlock()
+	return b.data.GetLength()
+}
+
+func (b *legacyBuffer) IsEmpty() bool {
+	return b.GetLength() == 0
+}
+
+func (b *legacyBuffer) IsClosed() bool {
+	select {
+	case <-b.close:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *legacyBuffer) Close() error {
+	if b.IsClosed() {
+		return data.ErrBufferAlreadyIsClosed
+	}
+	close(b.close)
+	return nil
+}
+
+func (b *legacyBuffer) checkConditions() {
+	select {
+	case <-b.ctx.Done():
+		_ = b.Close()
+		return
+	default:
+	}
+
+	checkCh := make(chan any)
+	go func() {
+		for b.maxSize < b.GetSize() || b.maxLength < b.GetLength() {
+			if b.IsClosed() {
+				checkCh <- 1
+				return
+			}
+		}
+		checkCh <- 1
+	}()
+
+	select {
+	case <-b.ctx.Done():
+		_ = b.Close()
+	case <-checkCh:
+	}
+}
@@ -0,0 +1,203 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/driver/cockroach"
+)
+
+// initializer is implemented by the value types that embed data.BaseValueType.
+type initializer interface {
+	Init(parent data.ValueType)
+}
+
+// newValue returns the value type with the given value parsed the way the readers parse it,
+// from a pointer to the scanned value. A nil value is a NULL.
+func newValue(t *testing.T, vt data.ValueType, v any) data.ValueType {
+	t.Helper()
+	vt.(initializer).Init(vt)
+	if err := vt.Parse(&v); err != nil {
+		t.Fatalf("%T.Parse(%v) error = %v", vt, v, err)
+	}
+	return vt
+}
+
+func newIDSet(t *testing.T, id int64) *data.Set {
+	set := data.NewDataSet()
+	set.Add(data.NewData("id", newValue(t, &cockroach.IntType{}, id)))
+	return set
+}
+
+func readID(t *testing.T, set *data.Set) int64 {
+	t.Helper()
+	return set.GetByIndex(0).GetValueType().GetValue().(int64)
+}
+
+func TestBufferKeepsOrderWhileGrowing(t *testing.T) {
+	buf := data.NewBuffer(context.Background())
+	var want []int64
+	read := func() {
+		t.Helper()
+		set, err := buf.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readID(t, set); got != want[0] {
+			t.Fatalf("Read() = %d, want %d", got, want[0])
+		}
+		want = want[1:]
+	}
+
+	for i := int64(0); i < 100; i++ {
+		if err := buf.Write(newIDSet(t, i)); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, i)
+		// reading every third data set wraps the ring around before it grows.
+		if i%3 == 0 {
+			read()
+		}
+	}
+	for len(want) > 0 {
+		read()
+	}
+	if buf.GetLength() != 0 || buf.GetSize() != 0 {
+		t.Errorf("drained buffer length = %d, size = %d", buf.GetLength(), buf.GetSize())
+	}
+}
+
+func TestBufferReadsInWriteOrder(t *testing.T) {
+	buf := data.NewBuffer(context.Background())
+	batch := data.Batch{newIDSet(t, 0), newIDSet(t, 1), newIDSet(t, 2)}
+	if err := buf.WriteBatch(&batch); err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.Write(newIDSet(t, 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := buf.ReadBatch(3)
+	if err != nil || got.GetLength() != 3 {
+		t.Fatalf("ReadBatch(3) = %v, %v, want 3 data sets", got, err)
+	}
+	for i, set := range *got {
+		if id := readID(t, set); id != int64(i) {
+			t.Errorf("ReadBatch(3)[%d] = %d, want %d", i, id, i)
+		}
+	}
+
+	// ReadBatch doesn't wait for a full batch.
+	got, err = buf.ReadBatch(10)
+	if err != nil || got.GetLength() != 1 || readID(t, (*got)[0]) != 3 {
+		t.Fatalf("ReadBatch(10) = %v, %v, want the last data set", got, err)
+	}
+}
+
+func TestBufferBlocksWritersWhenFull(t *testing.T) {
+	buf := data.NewBuffer(context.Background())
+	buf.Length(2)
+	for i := 0; i < 3; i++ {
+		if err := buf.Write(newIDSet(t, int64(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	written := make(chan error)
+	go func() {
+		written <- buf.Write(newIDSet(t, 3))
+	}()
+
+	select {
+	case err := <-written:
+		t.Fatalf("Write() to a full buffer returned %v without blocking", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if _, err := buf.Read(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Write() wasn't unblocked by Read()")
+	}
+}
+
+func TestBufferDrainsAfterClose(t *testing.T) {
+	buf := data.NewBuffer(context.Background())
+	if err := buf.Write(newIDSet(t, 0), newIDSet(t, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.Close(); !errors.Is(err, data.ErrBufferAlreadyIsClosed) {
+		t.Errorf("Close() twice error = %v, want %v", err, data.ErrBufferAlreadyIsClosed)
+	}
+	if err := buf.Write(newIDSet(t, 2)); !errors.Is(err, data.ErrBufferIsClosed) {
+		t.Errorf("Write() after Close() error = %v, want %v", err, data.ErrBufferIsClosed)
+	}
+
+	for i := 0; i < 2; i++ {
+		set, err := buf.Read()
+		if err != nil || readID(t, set) != int64(i) {
+			t.Fatalf("Read() after Close() = %v, %v, want %d", set, err, i)
+		}
+	}
+	if _, err := buf.Read(); !errors.Is(err, data.ErrBufferIsClosed) {
+		t.Errorf("Read() of a drained buffer error = %v, want %v", err, data.ErrBufferIsClosed)
+	}
+}
+
+func TestBufferClosedByContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	buf := data.NewBuffer(ctx)
+
+	read := make(chan error)
+	go func() {
+		_, err := buf.Read()
+		read <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-read:
+		if !errors.Is(err, data.ErrBufferIsClosed) {
+			t.Errorf("Read() error = %v, want %v", err, data.ErrBufferIsClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read() wasn't unblocked by the canceled context")
+	}
+}
+
+type countingObserver struct {
+	written, read int
+	length        uint64
+}
+
+func (o *countingObserver) SizeChanged(uint64)     {}
+func (o *countingObserver) LengthChanged(l uint64) { o.length = l }
+func (o *countingObserver) Write(n int)            { o.written += n }
+func (o *countingObserver) Read(n int)             { o.read += n }
+
+func TestBufferObserver(t *testing.T) {
+	o := &countingObserver{}
+	buf := data.NewBuffer(context.Background()).WithObserver(o)
+
+	if err := buf.Write(newIDSet(t, 0), nil, newIDSet(t, 1), newIDSet(t, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buf.ReadBatch(2); err != nil {
+		t.Fatal(err)
+	}
+	if o.written != 3 || o.read != 2 || o.length != 1 {
+		t.Errorf("observed %d written, %d read, length %d, want 3, 2 and 1", o.written, o.read, o.length)
+	}
+}
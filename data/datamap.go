@@ -83,6 +83,7 @@ func (m *Map) Delete(key string) {
 	}
 	delete(m.typesMap, key)
 	delete(m.isNullableMap, key)
+	delete(m.hasDefaultValue, key)
 }
 
 // Has returns true if the data type with the given name exists.
@@ -15,3 +15,8 @@ var (
 	ErrBufferAlreadyIsClosed = errors.New("buffer already is closed")
 	ErrBufferIsClosed        = errors.New("buffer is closed")
 )
+
+var (
+	ErrSpillUnsupportedValue = errors.New("spill: unsupported value type")
+	ErrSpillCorrupted        = errors.New("spill: corrupted segment")
+)
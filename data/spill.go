@@ -0,0 +1,534 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// DiskQuota is the maximum number of bytes that can be spilled to disk.
+// It can be shared among multiple spills, so all of them together respect the same limit.
+type DiskQuota struct {
+	limit uint64
+	used  *uint64
+}
+
+// NewDiskQuota returns a new disk quota with the given limit in bytes.
+// A zero limit means unlimited.
+func NewDiskQuota(limit uint64) *DiskQuota {
+	return &DiskQuota{
+		limit: limit,
+		used:  new(uint64),
+	}
+}
+
+// Reserve reserves n bytes of the quota.
+// It returns false if the quota doesn't have enough free space.
+func (q *DiskQuota) Reserve(n uint64) bool {
+	for {
+		used := atomic.LoadUint64(q.used)
+		if q.limit > 0 && used+n > q.limit {
+			return false
+		}
+		if atomic.CompareAndSwapUint64(q.used, used, used+n) {
+			return true
+		}
+	}
+}
+
+// Release releases n bytes of the quota.
+func (q *DiskQuota) Release(n uint64) {
+	atomic.AddUint64(q.used, ^(n - 1))
+}
+
+// Used returns the number of reserved bytes.
+func (q *DiskQuota) Used() uint64 {
+	return atomic.LoadUint64(q.used)
+}
+
+// value tags of the spill encoding.
+const (
+	spillTagNil byte = iota
+	spillTagFalse
+	spillTagTrue
+	spillTagInt
+	spillTagInt8
+	spillTagInt16
+	spillTagInt32
+	spillTagInt64
+	spillTagUint
+	spillTagUint8
+	spillTagUint16
+	spillTagUint32
+	spillTagUint64
+	spillTagFloat32
+	spillTagFloat64
+	spillTagString
+	spillTagBytes
+	spillTagTime
+	spillTagArray
+	spillTagBools
+	spillTagMap
+)
+
+// spillType is a data key with the value type prototype that is used to decode its values.
+type spillType struct {
+	key   string
+	proto ValueType
+}
+
+// spillSegment is a file that holds data sets of one spilled write, in the order they were written.
+type spillSegment struct {
+	path   string
+	size   uint64
+	length uint64
+}
+
+// Spill is a disk-backed FIFO queue of data sets. It's used by the Buffer to hold overflowing data sets.
+// Data sets are encoded in a compact binary format, where each value is stored with its Go type tag
+// and a reference to the value type it belongs to. Value types are kept in memory
+// and used as prototypes to decode the values back, so a Spill can only decode data sets it encoded itself.
+// Spill is not thread-safe, it's protected by the Buffer lock.
+type Spill struct {
+	dir      string
+	quota    *DiskQuota
+	segments []spillSegment
+	nextID   uint64
+	size     uint64
+	length   uint64
+	types    []spillType
+	typeIDs  map[string]uint64
+}
+
+// NewSpill creates a new spill in a new temporary directory inside the given directory.
+// If the dir is empty, the default directory for temporary files is used.
+// If no quota is given, the spill is unlimited.
+func NewSpill(dir string, quota ...*DiskQuota) (*Spill, error) {
+	d, err := os.MkdirTemp(dir, "gloader-spill-*")
+	if err != nil {
+		return nil, err
+	}
+
+	q := NewDiskQuota(0)
+	if len(quota) > 0 && quota[0] != nil {
+		q = quota[0]
+	}
+
+	return &Spill{
+		dir:     d,
+		quota:   q,
+		typeIDs: make(map[string]uint64),
+	}, nil
+}
+
+// GetSize returns the size of the spilled data sets on disk in bytes.
+func (s *Spill) GetSize() uint64 {
+	return s.size
+}
+
+// GetLength returns the number of spilled data sets.
+func (s *Spill) GetLength() uint64 {
+	return s.length
+}
+
+// IsEmpty returns true if there is no spilled data set.
+func (s *Spill) IsEmpty() bool {
+	return len(s.segments) == 0
+}
+
+// Encode encodes the given data sets. The nil data sets are skipped, like the Buffer skips them.
+func (s *Spill) Encode(sets []*Set) ([]byte, error) {
+	var buf bytes.Buffer
+	putUvarint(&buf, uint64(countSets(sets)))
+	for _, set := range sets {
+		if set == nil {
+			continue
+		}
+		putUvarint(&buf, uint64(set.GetLength()))
+		for _, d := range *set {
+			putUvarint(&buf, s.typeID(d))
+			var v any
+			if vt := d.GetValueType(); vt != nil {
+				v = vt.GetValue()
+			}
+			if err := encodeSpillValue(&buf, v); err != nil {
+				return nil, fmt.Errorf("%w: %s", err, d.GetKey())
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Push writes the given encoded data sets to a new segment at the end of the spill.
+// It returns false if the disk quota doesn't have enough free space.
+func (s *Spill) Push(encoded []byte, length uint64) (bool, error) {
+	size := uint64(len(encoded))
+	if !s.quota.Reserve(size) {
+		return false, nil
+	}
+
+	path := filepath.Join(s.dir, strconv.FormatUint(s.nextID, 10)+".seg")
+	if err := os.WriteFile(path, encoded, 0o600); err != nil {
+		s.quota.Release(size)
+		return false, err
+	}
+
+	s.nextID++
+	s.segments = append(s.segments, spillSegment{path: path, size: size, length: length})
+	s.size += size
+	s.length += length
+	return true, nil
+}
+
+// Pop reads and removes the first segment of the spill, and returns its data sets.
+func (s *Spill) Pop() ([]*Set, error) {
+	if len(s.segments) == 0 {
+		return nil, nil
+	}
+
+	segment := s.segments[0]
+	f, err := os.Open(segment.path)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := s.decode(bufio.NewReader(f))
+	_ = f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(segment.path); err != nil {
+		return nil, err
+	}
+
+	s.segments = s.segments[1:]
+	s.size -= segment.size
+	s.length -= segment.length
+	s.quota.Release(segment.size)
+	return sets, nil
+}
+
+// Clear removes all the spilled data sets.
+func (s *Spill) Clear() error {
+	for _, segment := range s.segments {
+		if err := os.Remove(segment.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.quota.Release(segment.size)
+	}
+	s.segments = nil
+	s.size = 0
+	s.length = 0
+	return nil
+}
+
+// Close removes all the spilled data sets and the spill directory.
+func (s *Spill) Close() error {
+	if err := s.Clear(); err != nil {
+		return err
+	}
+	return os.RemoveAll(s.dir)
+}
+
+func (s *Spill) typeID(d *Data) uint64 {
+	vt := d.GetValueType()
+	// a data without a value type is kept without one.
+	k := d.GetKey() + "\x00"
+	if vt != nil {
+		k += reflect.TypeOf(vt).String()
+	}
+	if id, ok := s.typeIDs[k]; ok {
+		return id
+	}
+
+	id := uint64(len(s.types))
+	s.types = append(s.types, spillType{key: d.GetKey(), proto: vt})
+	s.typeIDs[k] = id
+	return id
+}
+
+func (s *Spill) decode(r *bufio.Reader) ([]*Set, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make([]*Set, 0, n)
+	for i := uint64(0); i < n; i++ {
+		l, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		set := make(Set, 0, l)
+		for j := uint64(0); j < l; j++ {
+			id, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+			if id >= uint64(len(s.types)) {
+				return nil, ErrSpillCorrupted
+			}
+
+			v, err := decodeSpillValue(r)
+			if err != nil {
+				return nil, err
+			}
+
+			t := s.types[id]
+			if t.proto == nil {
+				set = append(set, NewData(t.key, nil))
+				continue
+			}
+			vt := GetNewValueTypeAs(t.proto)
+			(&BaseValueType{}).initIfHasFunc(vt)
+			// a NULL is the zero value of the value type, which the value types can't parse from nil.
+			if v != nil {
+				if err := vt.Parse(v); err != nil {
+					return nil, err
+				}
+			}
+			set = append(set, NewData(t.key, vt))
+		}
+		sets = append(sets, &set)
+	}
+	return sets, nil
+}
+
+func putUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func putVarint(buf *bytes.Buffer, v int64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func encodeSpillValue(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(spillTagNil)
+	case bool:
+		if v {
+			buf.WriteByte(spillTagTrue)
+		} else {
+			buf.WriteByte(spillTagFalse)
+		}
+	case int:
+		buf.WriteByte(spillTagInt)
+		putVarint(buf, int64(v))
+	case int8:
+		buf.WriteByte(spillTagInt8)
+		putVarint(buf, int64(v))
+	case int16:
+		buf.WriteByte(spillTagInt16)
+		putVarint(buf, int64(v))
+	case int32:
+		buf.WriteByte(spillTagInt32)
+		putVarint(buf, int64(v))
+	case int64:
+		buf.WriteByte(spillTagInt64)
+		putVarint(buf, v)
+	case uint:
+		buf.WriteByte(spillTagUint)
+		putUvarint(buf, uint64(v))
+	case uint8:
+		buf.WriteByte(spillTagUint8)
+		putUvarint(buf, uint64(v))
+	case uint16:
+		buf.WriteByte(spillTagUint16)
+		putUvarint(buf, uint64(v))
+	case uint32:
+		buf.WriteByte(spillTagUint32)
+		putUvarint(buf, uint64(v))
+	case uint64:
+		buf.WriteByte(spillTagUint64)
+		putUvarint(buf, v)
+	case float32:
+		buf.WriteByte(spillTagFloat32)
+		putUvarint(buf, uint64(math.Float32bits(v)))
+	case float64:
+		buf.WriteByte(spillTagFloat64)
+		putUvarint(buf, math.Float64bits(v))
+	case string:
+		buf.WriteByte(spillTagString)
+		putUvarint(buf, uint64(len(v)))
+		buf.WriteString(v)
+	case []byte:
+		buf.WriteByte(spillTagBytes)
+		putUvarint(buf, uint64(len(v)))
+		buf.Write(v)
+	case time.Time:
+		buf.WriteByte(spillTagTime)
+		b, err := v.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		putUvarint(buf, uint64(len(b)))
+		buf.Write(b)
+	case []any:
+		buf.WriteByte(spillTagArray)
+		putUvarint(buf, uint64(len(v)))
+		for _, e := range v {
+			if err := encodeSpillValue(buf, e); err != nil {
+				return err
+			}
+		}
+	case []bool:
+		buf.WriteByte(spillTagBools)
+		putUvarint(buf, uint64(len(v)))
+		for _, e := range v {
+			if e {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+		}
+	case map[string]any:
+		buf.WriteByte(spillTagMap)
+		putUvarint(buf, uint64(len(v)))
+		for k, e := range v {
+			putUvarint(buf, uint64(len(k)))
+			buf.WriteString(k)
+			if err := encodeSpillValue(buf, e); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %T", ErrSpillUnsupportedValue, v)
+	}
+	return nil
+}
+
+func decodeSpillValue(r *bufio.Reader) (any, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case spillTagNil:
+		return nil, nil
+	case spillTagFalse:
+		return false, nil
+	case spillTagTrue:
+		return true, nil
+	case spillTagInt, spillTagInt8, spillTagInt16, spillTagInt32, spillTagInt64:
+		v, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case spillTagInt:
+			return int(v), nil
+		case spillTagInt8:
+			return int8(v), nil
+		case spillTagInt16:
+			return int16(v), nil
+		case spillTagInt32:
+			return int32(v), nil
+		default:
+			return v, nil
+		}
+	case spillTagUint, spillTagUint8, spillTagUint16, spillTagUint32, spillTagUint64, spillTagFloat32, spillTagFloat64:
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case spillTagUint:
+			return uint(v), nil
+		case spillTagUint8:
+			return uint8(v), nil
+		case spillTagUint16:
+			return uint16(v), nil
+		case spillTagUint32:
+			return uint32(v), nil
+		case spillTagFloat32:
+			return math.Float32frombits(uint32(v)), nil
+		case spillTagFloat64:
+			return math.Float64frombits(v), nil
+		default:
+			return v, nil
+		}
+	case spillTagString, spillTagBytes, spillTagTime:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		switch tag {
+		case spillTagString:
+			return string(b), nil
+		case spillTagBytes:
+			return b, nil
+		default:
+			var t time.Time
+			if err := t.UnmarshalBinary(b); err != nil {
+				return nil, err
+			}
+			return t, nil
+		}
+	case spillTagArray:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		v := make([]any, n)
+		for i := range v {
+			if v[i], err = decodeSpillValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case spillTagBools:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		v := make([]bool, n)
+		for i := range v {
+			v[i] = b[i] == 1
+		}
+		return v, nil
+	case spillTagMap:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		v := make(map[string]any, n)
+		for i := uint64(0); i < n; i++ {
+			l, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+			k := make([]byte, l)
+			if _, err := io.ReadFull(r, k); err != nil {
+				return nil, err
+			}
+			if v[string(k)], err = decodeSpillValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	default:
+		return nil, ErrSpillCorrupted
+	}
+}
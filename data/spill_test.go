@@ -0,0 +1,305 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/driver/cockroach"
+	"github.com/mohammadv184/gloader/driver/mysql"
+)
+
+func TestSpillRoundTrip(t *testing.T) {
+	now := time.Date(2023, 5, 17, 10, 11, 12, 123456000, time.UTC)
+	tests := []struct {
+		name  string
+		value func() data.ValueType
+		v     any
+	}{
+		{"mysql char", func() data.ValueType { return &mysql.CharType{} }, "c"},
+		{"mysql varchar", func() data.ValueType { return &mysql.VarCharType{} }, "varchar"},
+		{"mysql binary", func() data.ValueType { return &mysql.BinaryType{} }, []byte{0, 1, 2}},
+		{"mysql text", func() data.ValueType { return &mysql.TextType{} }, "text"},
+		{"mysql smallint", func() data.ValueType { return &mysql.SmallIntType{} }, int16(-12)},
+		{"mysql int", func() data.ValueType { return &mysql.IntType{} }, int32(-1234)},
+		{"mysql tinyint", func() data.ValueType { return &mysql.TinyIntType{} }, true},
+		{"mysql bigint", func() data.ValueType { return &mysql.BigIntType{} }, int64(1) << 60},
+		{"mysql decimal", func() data.ValueType { return &mysql.DecimalType{} }, 12.5},
+		{"mysql longblob", func() data.ValueType { return &mysql.LongBlobType{} }, []byte("longblob")},
+		{"mysql mediumblob", func() data.ValueType { return &mysql.MediumBlobType{} }, []byte("mediumblob")},
+		{"mysql enum", func() data.ValueType { return &mysql.EnumType{} }, "small"},
+		{"mysql datetime", func() data.ValueType { return &mysql.DateTimeType{} }, now},
+		{"mysql blob", func() data.ValueType { return &mysql.BlobType{} }, []byte("blob")},
+		{"mysql mediumtext", func() data.ValueType { return &mysql.MediumTextType{} }, "mediumtext"},
+		{"mysql longtext", func() data.ValueType { return &mysql.LongTextType{} }, "longtext"},
+		{"mysql float", func() data.ValueType { return &mysql.FloatType{} }, float32(1.25)},
+		{"mysql double", func() data.ValueType { return &mysql.DoubleType{} }, 3.125},
+		{"mysql date", func() data.ValueType { return &mysql.DateType{} }, now.Truncate(24 * time.Hour)},
+		{"mysql timestamp", func() data.ValueType { return &mysql.TimestampType{} }, now},
+		{"cockroach array", func() data.ValueType { return &cockroach.ArrayType{} }, []any{int64(1), "two", nil}},
+		{"cockroach bit", func() data.ValueType { return &cockroach.BitType{} }, []bool{true, false, true}},
+		{"cockroach bool", func() data.ValueType { return &cockroach.BoolType{} }, false},
+		{"cockroach bytes", func() data.ValueType { return &cockroach.BytesType{} }, []byte("bytes")},
+		{"cockroach date", func() data.ValueType { return &cockroach.DateType{} }, now.Truncate(24 * time.Hour)},
+		{"cockroach jsonb", func() data.ValueType { return &cockroach.JSONBType{} }, `{"a":[1,"b",true,null],"c":{"d":2.5}}`},
+		{"cockroach uuid", func() data.ValueType { return &cockroach.UUIDType{} }, "2f1e8f5e-3e0e-4d4e-9a4c-1f2e3d4c5b6a"},
+		{"cockroach string", func() data.ValueType { return &cockroach.StringType{} }, "string"},
+		{"cockroach timestamp", func() data.ValueType { return &cockroach.TimestampType{} }, now},
+		{"cockroach int", func() data.ValueType { return &cockroach.IntType{} }, int64(-42)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := newValue(t, tt.value(), tt.v)
+			null := newValue(t, tt.value(), nil)
+
+			set := data.NewDataSet()
+			set.Add(data.NewData("value", value))
+			set.Add(data.NewData("null", null))
+
+			got := spillRoundTrip(t, set)
+			if got.GetLength() != 2 {
+				t.Fatalf("decoded %d data, want 2", got.GetLength())
+			}
+			for i, want := range []data.ValueType{value, null} {
+				d := got.GetByIndex(i)
+				if d.GetKey() != set.GetByIndex(i).GetKey() {
+					t.Errorf("key = %q, want %q", d.GetKey(), set.GetByIndex(i).GetKey())
+				}
+				if reflect.TypeOf(d.GetValueType()) != reflect.TypeOf(want) {
+					t.Errorf("%s value type = %T, want %T", d.GetKey(), d.GetValueType(), want)
+				}
+				if !reflect.DeepEqual(d.GetValueType().GetValue(), want.GetValue()) {
+					t.Errorf("%s value = %#v, want %#v", d.GetKey(), d.GetValueType().GetValue(), want.GetValue())
+				}
+			}
+		})
+	}
+}
+
+func TestSpillRoundTripWithoutValueType(t *testing.T) {
+	set := data.NewDataSet()
+	set.Add(data.NewData("nothing", nil))
+
+	got := spillRoundTrip(t, set)
+	if got.GetLength() != 1 || got.GetByIndex(0).GetKey() != "nothing" || got.GetByIndex(0).GetValueType() != nil {
+		t.Errorf("decoded %v, want a data without a value type", got)
+	}
+}
+
+func TestSpillKeepsOrderAndQuota(t *testing.T) {
+	quota := data.NewDiskQuota(0)
+	spill, err := data.NewSpill(t.TempDir(), quota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Close()
+
+	for i := 0; i < 3; i++ {
+		encoded, err := spill.Encode([]*data.Set{newIDSet(t, int64(i*2)), newIDSet(t, int64(i*2+1))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := spill.Push(encoded, 2); !ok || err != nil {
+			t.Fatalf("Push() = %v, %v", ok, err)
+		}
+	}
+	if spill.GetLength() != 6 || quota.Used() != spill.GetSize() {
+		t.Fatalf("length = %d, used quota = %d, size = %d", spill.GetLength(), quota.Used(), spill.GetSize())
+	}
+
+	var next int64
+	for !spill.IsEmpty() {
+		sets, err := spill.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, set := range sets {
+			if got := set.GetByIndex(0).GetValueType().GetValue(); got != next {
+				t.Fatalf("popped %v, want %d", got, next)
+			}
+			next++
+		}
+	}
+	if next != 6 || quota.Used() != 0 {
+		t.Errorf("popped %d data sets, used quota = %d, want 6 and 0", next, quota.Used())
+	}
+}
+
+func TestSpillPushOverQuota(t *testing.T) {
+	spill, err := data.NewSpill(t.TempDir(), data.NewDiskQuota(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Close()
+
+	encoded, err := spill.Encode([]*data.Set{newIDSet(t, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := spill.Push(encoded, 1); ok || err != nil {
+		t.Errorf("Push() over the quota = %v, %v, want false", ok, err)
+	}
+}
+
+func TestSpillEncodeUnsupportedValue(t *testing.T) {
+	spill, err := data.NewSpill(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Close()
+
+	set := data.NewDataSet()
+	set.Add(data.NewData("channel", &unsupportedValue{}))
+	if _, err := spill.Encode([]*data.Set{set}); !errors.Is(err, data.ErrSpillUnsupportedValue) {
+		t.Errorf("Encode() error = %v, want %v", err, data.ErrSpillUnsupportedValue)
+	}
+}
+
+func spillRoundTrip(t *testing.T, set *data.Set) *data.Set {
+	t.Helper()
+	spill, err := data.NewSpill(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Close()
+
+	encoded, err := spill.Encode([]*data.Set{set})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if ok, err := spill.Push(encoded, 1); !ok || err != nil {
+		t.Fatalf("Push() = %v, %v", ok, err)
+	}
+	sets, err := spill.Pop()
+	if err != nil {
+		t.Fatalf("Pop() error = %v", err)
+	}
+	if len(sets) != 1 {
+		t.Fatalf("Pop() returned %d data sets, want 1", len(sets))
+	}
+	return sets[0]
+}
+
+type unsupportedValue struct {
+	data.BaseValueType
+}
+
+func (v *unsupportedValue) GetValue() any {
+	return make(chan int)
+}
+
+func TestBufferSpillsNullsInOrder(t *testing.T) {
+	spill, err := data.NewSpill(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Close()
+
+	buf := data.NewBuffer(context.Background()).WithSpill(spill)
+	buf.Length(1)
+	for i := int64(0); i < 10; i++ {
+		set := newIDSet(t, i)
+		set.Add(data.NewData("deleted_at", newValue(t, &mysql.DateTimeType{}, nil)))
+		if err := buf.Write(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if spill.IsEmpty() {
+		t.Fatal("nothing was spilled")
+	}
+	if err := buf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := int64(0); i < 10; i++ {
+		set, err := buf.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readID(t, set); got != i {
+			t.Fatalf("Read() = %d, want %d", got, i)
+		}
+		if v := set.Get("deleted_at").GetValueType().GetValue(); v != nil {
+			t.Fatalf("deleted_at = %v, want NULL", v)
+		}
+	}
+	if !spill.IsEmpty() {
+		t.Error("the spill wasn't drained")
+	}
+}
+
+func TestSpillEncodeSkipsNilSets(t *testing.T) {
+	spill, err := data.NewSpill(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Close()
+
+	encoded, err := spill.Encode([]*data.Set{nil, newIDSet(t, 1), nil})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if ok, err := spill.Push(encoded, 1); !ok || err != nil {
+		t.Fatalf("Push() = %v, %v", ok, err)
+	}
+	sets, err := spill.Pop()
+	if err != nil {
+		t.Fatalf("Pop() error = %v", err)
+	}
+	if len(sets) != 1 || readID(t, sets[0]) != 1 {
+		t.Errorf("Pop() = %v, want the data set 1", sets)
+	}
+}
+
+func TestBufferDoesntSpillEmptyWrites(t *testing.T) {
+	spill, err := data.NewSpill(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Close()
+
+	buf := data.NewBuffer(context.Background()).WithSpill(spill)
+	buf.Length(0)
+	if err := buf.Write(newIDSet(t, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// the buffer is full, so the empty writes would be spilled.
+	if err := buf.Write(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if !spill.IsEmpty() || buf.GetLength() != 1 {
+		t.Fatalf("spill is empty = %v, length = %d, want true and 1", spill.IsEmpty(), buf.GetLength())
+	}
+
+	if set, err := buf.Read(); err != nil || readID(t, set) != 0 {
+		t.Fatalf("Read() = %v, %v, want 0", set, err)
+	}
+
+	// the open buffer is drained, so the reader waits for the next data set.
+	read := make(chan error, 1)
+	go func() {
+		set, err := buf.Read()
+		if err == nil && readID(t, set) != 1 {
+			err = errors.New("read the wrong data set")
+		}
+		read <- err
+	}()
+	select {
+	case err := <-read:
+		t.Fatalf("Read() on an open drained buffer returned %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := buf.Write(newIDSet(t, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-read; err != nil {
+		t.Errorf("Read() error = %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package gloader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mohammadv184/gloader/driver"
+)
+
+var (
+	ErrForeignKeyCycle       = errors.New("foreign key cycle detected")
+	ErrUnknownForeignKeyMode = errors.New("unknown foreign key mode")
+)
+
+// ForeignKeyMode determines how foreign key relationships between data collections are handled.
+type ForeignKeyMode uint8
+
+const (
+	// ForeignKeyOrder loads data collections in topological waves, so parents are loaded before their children.
+	// Cycles are reported as ErrForeignKeyCycle.
+	ForeignKeyOrder ForeignKeyMode = iota
+	// ForeignKeyIgnore loads all data collections at the same time regardless of their relationships.
+	ForeignKeyIgnore
+)
+
+var foreignKeyModeNames = map[ForeignKeyMode]string{
+	ForeignKeyOrder:  "order",
+	ForeignKeyIgnore: "ignore",
+}
+
+// String returns the name of the foreign key mode.
+func (m ForeignKeyMode) String() string {
+	return foreignKeyModeNames[m]
+}
+
+// GetForeignKeyModeFromString returns the foreign key mode from its name.
+func GetForeignKeyModeFromString(mode string) (ForeignKeyMode, error) {
+	for k, v := range foreignKeyModeNames {
+		if strings.EqualFold(v, mode) {
+			return k, nil
+		}
+	}
+	return ForeignKeyOrder, fmt.Errorf("%w: %s", ErrUnknownForeignKeyMode, mode)
+}
+
+// dependencyGraph is a directed graph of data collections, where each edge points from a child to its parent.
+// References to data collections outside the graph are ignored, since they are not loaded by this run.
+type dependencyGraph struct {
+	nodes   []driver.DataCollectionDetail
+	parents map[string][]string
+}
+
+// newDependencyGraph returns the graph of the given data collections,
+// where references maps each data collection to the names of the data collections it references.
+func newDependencyGraph(dcs []driver.DataCollectionDetail, references map[string][]string) *dependencyGraph {
+	g := &dependencyGraph{
+		nodes:   dcs,
+		parents: make(map[string][]string, len(dcs)),
+	}
+
+	for _, dc := range dcs {
+		g.parents[dc.Name] = nil
+	}
+
+	for _, dc := range dcs {
+		for _, parent := range references[dc.Name] {
+			if _, ok := g.parents[parent]; !ok || parent == dc.Name {
+				continue
+			}
+			g.parents[dc.Name] = append(g.parents[dc.Name], parent)
+		}
+	}
+	return g
+}
+
+// Waves returns the data collections grouped in waves,
+// every data collection in a wave only depends on data collections in the previous waves.
+// If the graph has a cycle, ErrForeignKeyCycle is returned with the data collections that form it.
+func (g *dependencyGraph) Waves() ([][]driver.DataCollectionDetail, error) {
+	loaded := make(map[string]bool, len(g.nodes))
+	var waves [][]driver.DataCollectionDetail
+
+	for len(loaded) < len(g.nodes) {
+		var wave []driver.DataCollectionDetail
+		for _, dc := range g.nodes {
+			if loaded[dc.Name] {
+				continue
+			}
+
+			ready := true
+			for _, parent := range g.parents[dc.Name] {
+				if !loaded[parent] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				wave = append(wave, dc)
+			}
+		}
+
+		if len(wave) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrForeignKeyCycle, strings.Join(g.findCycle(loaded), " -> "))
+		}
+
+		for _, dc := range wave {
+			loaded[dc.Name] = true
+		}
+		waves = append(waves, wave)
+	}
+	return waves, nil
+}
+
+// findCycle returns a cycle between the data collections that are not loaded yet.
+// The first data collection of the cycle is repeated at the end of it.
+func (g *dependencyGraph) findCycle(loaded map[string]bool) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.nodes))
+	var stack []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		stack = append(stack, name)
+
+		for _, parent := range g.parents[name] {
+			if loaded[parent] {
+				continue
+			}
+
+			switch state[parent] {
+			case visiting:
+				for i, n := range stack {
+					if n == parent {
+						return append(append([]string{}, stack[i:]...), parent)
+					}
+				}
+			case unvisited:
+				if cycle := visit(parent); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, dc := range g.nodes {
+		if loaded[dc.Name] || state[dc.Name] != unvisited {
+			continue
+		}
+		if cycle := visit(dc.Name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package gloader
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mohammadv184/gloader/driver"
+)
+
+func dcWithParents(name string, parents ...string) driver.DataCollectionDetail {
+	dc := driver.DataCollectionDetail{Name: name}
+	for _, parent := range parents {
+		dc.ForeignKeys = append(dc.ForeignKeys, driver.ForeignKey{
+			Name:                     name + "_" + parent + "_fk",
+			Keys:                     []string{parent + "_id"},
+			ReferencedDataCollection: parent,
+			ReferencedKeys:           []string{"id"},
+		})
+	}
+	return dc
+}
+
+// referencesOf returns the data collections referenced by the foreign keys of each data collection.
+func referencesOf(dcs []driver.DataCollectionDetail) map[string][]string {
+	references := make(map[string][]string, len(dcs))
+	for _, dc := range dcs {
+		for _, fk := range dc.ForeignKeys {
+			references[dc.Name] = append(references[dc.Name], fk.ReferencedDataCollection)
+		}
+	}
+	return references
+}
+
+func waveNames(waves [][]driver.DataCollectionDetail) [][]string {
+	names := make([][]string, 0, len(waves))
+	for _, wave := range waves {
+		var wn []string
+		for _, dc := range wave {
+			wn = append(wn, dc.Name)
+		}
+		names = append(names, wn)
+	}
+	return names
+}
+
+func TestDependencyGraphWaves(t *testing.T) {
+	tests := []struct {
+		name string
+		dcs  []driver.DataCollectionDetail
+		want [][]string
+	}{
+		{
+			name: "no relationships",
+			dcs:  []driver.DataCollectionDetail{dcWithParents("a"), dcWithParents("b")},
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "children after parents",
+			dcs: []driver.DataCollectionDetail{
+				dcWithParents("order_items", "orders", "products"),
+				dcWithParents("orders", "users"),
+				dcWithParents("products"),
+				dcWithParents("users"),
+			},
+			want: [][]string{{"products", "users"}, {"orders"}, {"order_items"}},
+		},
+		{
+			name: "self reference and unknown parent are ignored",
+			dcs: []driver.DataCollectionDetail{
+				dcWithParents("categories", "categories"),
+				dcWithParents("posts", "authors"),
+			},
+			want: [][]string{{"categories", "posts"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waves, err := newDependencyGraph(tt.dcs, referencesOf(tt.dcs)).Waves()
+			if err != nil {
+				t.Fatalf("Waves() error = %v", err)
+			}
+			if got := waveNames(waves); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Waves() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencyGraphWavesCycle(t *testing.T) {
+	dcs := []driver.DataCollectionDetail{
+		dcWithParents("users"),
+		dcWithParents("a", "c"),
+		dcWithParents("b", "a", "users"),
+		dcWithParents("c", "b"),
+	}
+
+	_, err := newDependencyGraph(dcs, referencesOf(dcs)).Waves()
+	if !errors.Is(err, ErrForeignKeyCycle) {
+		t.Fatalf("Waves() error = %v, want %v", err, ErrForeignKeyCycle)
+	}
+	if want := "foreign key cycle detected: a -> c -> b -> a"; err.Error() != want {
+		t.Errorf("Waves() error = %q, want %q", err, want)
+	}
+}
+
+func TestPlanWavesFollowsTheDestination(t *testing.T) {
+	// the source foreign keys are ignored, and the destination ones are translated to the source names.
+	dcs := []driver.DataCollectionDetail{
+		dcWithParents("customers"),
+		dcWithParents("purchases", "items"),
+		dcWithParents("items"),
+		dcWithParents("logs"),
+	}
+	dDetails := driver.DatabaseDetail{DataCollections: []driver.DataCollectionDetail{
+		dcWithParents("users"),
+		dcWithParents("orders", "users", "archived"),
+		dcWithParents("items"),
+	}}
+
+	g := NewGLoader().SetDestDataCollection("customers", "users").SetDestDataCollection("purchases", "orders")
+	waves, err := g.planWaves(dcs, dDetails)
+	if err != nil {
+		t.Fatalf("planWaves() error = %v", err)
+	}
+	want := [][]string{{"customers", "items", "logs"}, {"purchases"}}
+	if got := waveNames(waves); !reflect.DeepEqual(got, want) {
+		t.Errorf("planWaves() = %v, want %v", got, want)
+	}
+}
+
+func TestGetForeignKeyModeFromString(t *testing.T) {
+	for mode, name := range foreignKeyModeNames {
+		got, err := GetForeignKeyModeFromString(name)
+		if err != nil || got != mode {
+			t.Errorf("GetForeignKeyModeFromString(%q) = %v, %v, want %v", name, got, err, mode)
+		}
+	}
+	if got, err := GetForeignKeyModeFromString("IGNORE"); err != nil || got != ForeignKeyIgnore {
+		t.Errorf("GetForeignKeyModeFromString is case sensitive: %v, %v", got, err)
+	}
+	if _, err := GetForeignKeyModeFromString("defer"); !errors.Is(err, ErrUnknownForeignKeyMode) {
+		t.Errorf("GetForeignKeyModeFromString(defer) error = %v, want %v", err, ErrUnknownForeignKeyMode)
+	}
+}
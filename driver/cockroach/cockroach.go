@@ -5,6 +5,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/log"
 )
 
 // Cockroach is a driver for CockroachDB.
@@ -13,8 +14,7 @@ type Cockroach struct{}
 func init() {
 	err := driver.Register(&Cockroach{})
 	if err != nil {
-		// TODO: logging system
-		// log.Println(err)
+		log.Error("failed to register the cockroach driver", log.Err(err))
 	}
 }
 
@@ -47,5 +47,5 @@ func (*Cockroach) Open(ctx context.Context, dsn string) (driver.Connection, erro
 		return nil, err
 	}
 
-	return &Connection{conn: conn, config: config}, nil
+	return &Connection{conn: conn, config: config, logger: log.Default()}, nil
 }
@@ -5,14 +5,30 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/mohammadv184/gloader/data"
 	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// dialect is the SQL dialect of CockroachDB.
+type dialect struct{}
+
+var _ driver.Dialect = dialect{}
+
+func (dialect) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (dialect) Placeholder(position int) string {
+	return "$" + strconv.Itoa(position)
+}
+
 // Connection is a connection to a CockroachDB database.
 type Connection struct {
 	conn         *pgx.Conn
@@ -20,11 +36,17 @@ type Connection struct {
 	dbName       string
 	tableDetails map[string]driver.DataCollectionDetail
 	config       *Config
+	logger       *log.Logger
 
 	driver.DefaultFilterBuilder
 	driver.DefaultSortBuilder
 }
 
+// SetLogger sets the logger of the connection.
+func (c *Connection) SetLogger(logger *log.Logger) {
+	c.logger = logger
+}
+
 // Close closes the connection to the database.
 func (c *Connection) Close() error {
 	if c.isClosed {
@@ -53,6 +75,13 @@ func (c *Connection) IsClosed() bool {
 
 // GetDetails returns the details of the database.
 func (c *Connection) GetDetails(ctx context.Context) (driver.DatabaseDetail, error) {
+	ctx, span := driver.StartSpan(ctx, "cockroach.GetDetails", driver.AttributeDriver.String("cockroach"))
+	details, err := c.getDetails(ctx)
+	driver.EndSpan(span, err)
+	return details, err
+}
+
+func (c *Connection) getDetails(ctx context.Context) (driver.DatabaseDetail, error) {
 	if c.isClosed {
 		return driver.DatabaseDetail{}, driver.ErrConnectionIsClosed
 	}
@@ -99,6 +128,19 @@ func (c *Connection) GetDetails(ctx context.Context) (driver.DatabaseDetail, err
 
 // Write writes a batch of data to the database.
 func (c *Connection) Write(ctx context.Context, table string, dataBatch *data.Batch) error {
+	ctx, span := driver.StartSpan(
+		ctx,
+		"cockroach.Write",
+		driver.AttributeDriver.String("cockroach"),
+		driver.AttributeDataCollection.String(table),
+		driver.AttributeBatchRows.Int64(int64(dataBatch.GetLength())),
+	)
+	err := c.write(ctx, table, dataBatch)
+	driver.EndSpan(span, err)
+	return err
+}
+
+func (c *Connection) write(ctx context.Context, table string, dataBatch *data.Batch) error {
 	if c.isClosed {
 		return driver.ErrConnectionIsClosed
 	}
@@ -162,6 +204,8 @@ func (c *Connection) Write(ctx context.Context, table string, dataBatch *data.Ba
 		}
 	}
 
+	trace.SpanFromContext(ctx).AddEvent("data sets converted")
+
 	tx, err := c.conn.Begin(ctx)
 	if err != nil {
 		return err
@@ -181,7 +225,7 @@ func (c *Connection) Write(ctx context.Context, table string, dataBatch *data.Ba
 			var pgErr *pgconn.PgError
 			if errors.As(err, &pgErr) {
 				if pgErr.Code == "23505" { // 23505 is the unique_violation error code
-					fmt.Println("Unique violation detected: ", pgErr.Detail, table)
+					c.logger.Warn("unique violation detected", "table", table, "detail", pgErr.Detail)
 					// TODO: handle unique violation
 					//dupRow := regexp.MustCompile(`\((.*)\)=\((.*)\)`).FindStringSubmatch(pgErr.Detail)
 					//if len(dupRow) != 3 {
@@ -195,14 +239,20 @@ func (c *Connection) Write(ctx context.Context, table string, dataBatch *data.Ba
 					//	}
 					//}
 				}
-				fmt.Println("Error executing statement: ", pgErr.Code, pgErr.Message, pgErr.Detail, table)
+				c.logger.Error(
+					"failed to execute statement",
+					"table", table,
+					"code", pgErr.Code,
+					"message", pgErr.Message,
+					"detail", pgErr.Detail,
+				)
 			}
-			fmt.Println("Error executing statement")
 			return err
 		}
 
 	}
 
+	trace.SpanFromContext(ctx).AddEvent("data sets copied")
 	return tx.Commit(ctx)
 }
 
@@ -220,7 +270,7 @@ func (c *Connection) getTableDetails(ctx context.Context, table string) (driver.
 		DataMap: new(data.Map),
 	}
 
-	columns, err := c.conn.Query(ctx, fmt.Sprintf("SHOW COLUMNS FROM %s", table))
+	columns, err := c.conn.Query(ctx, "SHOW COLUMNS FROM "+dialect{}.QuoteIdentifier(table))
 	if err != nil {
 		return driver.DataCollectionDetail{}, err
 	}
@@ -246,7 +296,8 @@ func (c *Connection) getTableDetails(ctx context.Context, table string) (driver.
 
 	var count int
 
-	res := c.conn.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s %s", table, c.BuildFilterSQL(table)))
+	filterSQL, filterArgs := c.BuildFilterClause(dialect{}, table)
+	res := c.conn.QueryRow(ctx, "SELECT COUNT(*) FROM "+dialect{}.QuoteIdentifier(table)+filterSQL, filterArgs...)
 
 	err = res.Scan(&count)
 	if err != nil {
@@ -254,7 +305,53 @@ func (c *Connection) getTableDetails(ctx context.Context, table string) (driver.
 	}
 	dc.DataSetCount = count
 
+	dc.ForeignKeys, err = c.getForeignKeys(ctx, table)
+	if err != nil {
+		return driver.DataCollectionDetail{}, err
+	}
+
 	c.tableDetails[table] = dc
 
 	return dc, nil
 }
+
+func (c *Connection) getForeignKeys(ctx context.Context, table string) ([]driver.ForeignKey, error) {
+	rows, err := c.conn.Query(
+		ctx,
+		`SELECT kcu.constraint_name, kcu.column_name, rkcu.table_name, rkcu.column_name
+		FROM information_schema.referential_constraints rc
+		JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_schema = rc.constraint_schema AND kcu.constraint_name = rc.constraint_name
+		JOIN information_schema.key_column_usage rkcu
+			ON rkcu.constraint_schema = rc.unique_constraint_schema
+			AND rkcu.constraint_name = rc.unique_constraint_name
+			AND rkcu.ordinal_position = kcu.position_in_unique_constraint
+		WHERE kcu.table_schema = current_schema() AND kcu.table_name = $1
+		ORDER BY kcu.constraint_name, kcu.ordinal_position`,
+		table,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var foreignKeys []driver.ForeignKey
+	// the client will automatically close the rows when all the rows are read
+	for rows.Next() {
+		var constraintName, columnName, referencedTable, referencedColumn string
+		err = rows.Scan(&constraintName, &columnName, &referencedTable, &referencedColumn)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(foreignKeys) == 0 || foreignKeys[len(foreignKeys)-1].Name != constraintName {
+			foreignKeys = append(foreignKeys, driver.ForeignKey{
+				Name:                     constraintName,
+				ReferencedDataCollection: referencedTable,
+			})
+		}
+		fk := &foreignKeys[len(foreignKeys)-1]
+		fk.Keys = append(fk.Keys, columnName)
+		fk.ReferencedKeys = append(fk.ReferencedKeys, referencedColumn)
+	}
+	return foreignKeys, rows.Err()
+}
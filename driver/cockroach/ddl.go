@@ -0,0 +1,165 @@
+package cockroach
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/driver"
+)
+
+var (
+	_ driver.TruncatableConnection = &Connection{}
+	_ driver.DroppableConnection   = &Connection{}
+	_ driver.CreatableConnection   = &Connection{}
+	_ driver.RenamableConnection   = &Connection{}
+	_ driver.CopyableConnection    = &Connection{}
+	_ driver.CountableConnection   = &Connection{}
+)
+
+// TruncateDataCollection deletes all the rows of the table.
+func (c *Connection) TruncateDataCollection(ctx context.Context, table string) error {
+	return c.exec(ctx, "cockroach.TruncateDataCollection", table, "TRUNCATE TABLE "+dialect{}.QuoteIdentifier(table))
+}
+
+// DropDataCollection drops the table if it exists.
+func (c *Connection) DropDataCollection(ctx context.Context, table string) error {
+	return c.exec(ctx, "cockroach.DropDataCollection", table, "DROP TABLE IF EXISTS "+dialect{}.QuoteIdentifier(table))
+}
+
+// CreateDataCollection creates a table with the columns of the given details.
+// The column types are converted by their kinds, since the details may come from another database,
+// and the table has no primary key and indexes, so CockroachDB adds its hidden rowid primary key.
+func (c *Connection) CreateDataCollection(ctx context.Context, table string, detail driver.DataCollectionDetail) error {
+	columns := make([]string, 0, detail.DataMap.Len())
+	for _, key := range detail.DataMap.Keys() {
+		column := dialect{}.QuoteIdentifier(key) + " " + sqlTypeOf(detail.DataMap.Get(key))
+		if !detail.DataMap.IsNullable(key) {
+			column += " NOT NULL"
+		}
+		columns = append(columns, column)
+	}
+	return c.exec(
+		ctx,
+		"cockroach.CreateDataCollection",
+		table,
+		"CREATE TABLE "+dialect{}.QuoteIdentifier(table)+" ("+strings.Join(columns, ", ")+")",
+	)
+}
+
+// CreateDataCollectionLike creates an empty table with the columns, primary key and indexes of an existing one.
+// The foreign keys aren't copied, since LIKE doesn't copy them.
+func (c *Connection) CreateDataCollectionLike(ctx context.Context, table, like string) error {
+	return c.exec(
+		ctx,
+		"cockroach.CreateDataCollectionLike",
+		table,
+		"CREATE TABLE "+dialect{}.QuoteIdentifier(table)+" (LIKE "+dialect{}.QuoteIdentifier(like)+" INCLUDING ALL)",
+	)
+}
+
+// RenameDataCollections renames the tables in order in a transaction.
+func (c *Connection) RenameDataCollections(ctx context.Context, renames ...driver.Rename) error {
+	if c.isClosed {
+		return driver.ErrConnectionIsClosed
+	}
+	ctx, span := driver.StartSpan(ctx, "cockroach.RenameDataCollections", driver.AttributeDriver.String("cockroach"))
+
+	err := pgx.BeginFunc(ctx, c.conn, func(tx pgx.Tx) error {
+		for _, r := range renames {
+			sql := "ALTER TABLE "
+			if r.IfExists {
+				sql += "IF EXISTS "
+			}
+			_, err := tx.Exec(ctx, sql+dialect{}.QuoteIdentifier(r.From)+" RENAME TO "+dialect{}.QuoteIdentifier(r.To))
+			if err != nil {
+				return fmt.Errorf("failed to rename %s to %s: %w", r.From, r.To, err)
+			}
+		}
+		return nil
+	})
+	// the cached details of the renamed tables are outdated.
+	for _, r := range renames {
+		delete(c.tableDetails, r.From)
+		delete(c.tableDetails, r.To)
+	}
+
+	driver.EndSpan(span, err)
+	return err
+}
+
+// CopyDataCollection inserts all the rows of a table into another one with the same columns.
+func (c *Connection) CopyDataCollection(ctx context.Context, from, to string) error {
+	return c.exec(
+		ctx,
+		"cockroach.CopyDataCollection",
+		to,
+		"INSERT INTO "+dialect{}.QuoteIdentifier(to)+" SELECT * FROM "+dialect{}.QuoteIdentifier(from),
+	)
+}
+
+// CountDataSets returns the number of rows of the table.
+func (c *Connection) CountDataSets(ctx context.Context, table string) (uint64, error) {
+	if c.isClosed {
+		return 0, driver.ErrConnectionIsClosed
+	}
+	ctx, span := driver.StartSpan(
+		ctx,
+		"cockroach.CountDataSets",
+		driver.AttributeDriver.String("cockroach"),
+		driver.AttributeDataCollection.String(table),
+	)
+
+	var count uint64
+	err := c.conn.QueryRow(ctx, "SELECT COUNT(*) FROM "+dialect{}.QuoteIdentifier(table)).Scan(&count)
+
+	driver.EndSpan(span, err)
+	return count, err
+}
+
+// exec executes a DDL statement of the table, and forgets its cached details.
+func (c *Connection) exec(ctx context.Context, spanName, table, sql string) error {
+	if c.isClosed {
+		return driver.ErrConnectionIsClosed
+	}
+	ctx, span := driver.StartSpan(
+		ctx,
+		spanName,
+		driver.AttributeDriver.String("cockroach"),
+		driver.AttributeDataCollection.String(table),
+	)
+
+	_, err := c.conn.Exec(ctx, sql)
+	delete(c.tableDetails, table)
+
+	driver.EndSpan(span, err)
+	return err
+}
+
+// sqlTypeOf returns the CockroachDB type of the kind of the given type.
+func sqlTypeOf(t data.Type) string {
+	switch t.GetTypeKind() {
+	case data.KindBool:
+		return "BOOL"
+	case data.KindInt, data.KindInt8, data.KindInt16, data.KindInt32, data.KindInt64,
+		data.KindUint, data.KindUint8, data.KindUint16, data.KindUint32:
+		return "INT8"
+	case data.KindUint64:
+		// the unsigned 64-bit integers don't fit in INT8.
+		return "DECIMAL"
+	case data.KindFloat, data.KindFloat32, data.KindFloat64:
+		return "FLOAT8"
+	case data.KindTime, data.KindTimestamp:
+		return "TIMESTAMP"
+	case data.KindDuration:
+		return "INTERVAL"
+	case data.KindBytes:
+		return "BYTES"
+	case data.KindArray, data.KindSlice, data.KindMap, data.KindStruct:
+		return "JSONB"
+	default:
+		return "STRING"
+	}
+}
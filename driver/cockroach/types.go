@@ -365,6 +365,11 @@ func (t *JSONBType) Parse(v any) error {
 		}
 		t.hasValue = true
 		return nil
+	case map[string]any, []any, float64, bool:
+		// the values which are already decoded, e.g. by GetValue of another JSONBType.
+		t.value = tv
+		t.hasValue = true
+		return nil
 	default:
 		return fmt.Errorf("%v: expected []byte, got %T", data.ErrInvalidValue, v)
 	}
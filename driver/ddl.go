@@ -0,0 +1,59 @@
+package driver
+
+import "context"
+
+// TruncatableConnection is a connection that can delete all the data sets of a data collection.
+type TruncatableConnection interface {
+	Connection // Embeds Connection
+	// TruncateDataCollection deletes all the data sets of the data collection.
+	TruncateDataCollection(ctx context.Context, dataCollection string) error
+}
+
+// DroppableConnection is a connection that can drop data collections.
+type DroppableConnection interface {
+	Connection // Embeds Connection
+	// DropDataCollection drops the data collection if it exists.
+	DropDataCollection(ctx context.Context, dataCollection string) error
+}
+
+// CreatableConnection is a connection that can create data collections.
+type CreatableConnection interface {
+	Connection // Embeds Connection
+	// CreateDataCollection creates a data collection with the name and the columns of the given details,
+	// which may come from another database, so the column types are converted by their kinds.
+	// The details have no keys and indexes, so the data collection is created without them.
+	CreateDataCollection(ctx context.Context, dataCollection string, detail DataCollectionDetail) error
+	// CreateDataCollectionLike creates an empty data collection with the schema of an existing one,
+	// including its primary key and indexes, but not its foreign keys.
+	CreateDataCollectionLike(ctx context.Context, dataCollection, like string) error
+}
+
+// Rename is a rename of a data collection.
+type Rename struct {
+	From string
+	To   string
+	// IfExists skips the rename if the From data collection doesn't exist.
+	IfExists bool
+}
+
+// RenamableConnection is a connection that can rename data collections.
+type RenamableConnection interface {
+	Connection // Embeds Connection
+	// RenameDataCollections renames the data collections in order, atomically,
+	// so either all or none of them are renamed, and no one sees them in between.
+	RenameDataCollections(ctx context.Context, renames ...Rename) error
+}
+
+// CopyableConnection is a connection that can copy the data sets of a data collection into another one.
+type CopyableConnection interface {
+	Connection // Embeds Connection
+	// CopyDataCollection copies all the data sets of the data collection into another one with the same schema.
+	CopyDataCollection(ctx context.Context, from, to string) error
+}
+
+// CountableConnection is a connection that can count the data sets of a data collection.
+type CountableConnection interface {
+	Connection // Embeds Connection
+	// CountDataSets returns the exact number of the data sets of the data collection.
+	CountDataSets(ctx context.Context, dataCollection string) (uint64, error)
+}
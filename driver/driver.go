@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/pkg/log"
 )
 
 // Driver is a driver for a database.
@@ -45,6 +46,14 @@ type WritableConnection interface {
 	Write(ctx context.Context, dataCollection string, dataBatch *data.Batch) error
 }
 
+// LoggableConnection is a connection that logs with an injected logger.
+// The connections that don't implement it don't log.
+type LoggableConnection interface {
+	Connection // Embeds Connection
+	// SetLogger sets the logger of the connection.
+	SetLogger(logger *log.Logger)
+}
+
 // ReadableConnection is a connection to a database that can read data.
 type ReadableConnection interface {
 	Connection // Embeds Connection
@@ -116,11 +125,20 @@ func (d DatabaseDetail) AllDataCollectionsExcept(names ...string) []DataCollecti
 	return result
 }
 
+// ForeignKey is a reference from the keys of a data collection to the keys of another data collection.
+type ForeignKey struct {
+	Name                     string
+	Keys                     []string
+	ReferencedDataCollection string
+	ReferencedKeys           []string
+}
+
 // DataCollectionDetail is the details of a data collection.
 type DataCollectionDetail struct {
 	DataMap      *data.Map
 	Name         string
 	DataSetCount int
+	ForeignKeys  []ForeignKey
 }
 
 func (d DataCollectionDetail) GetDataMap() *data.Map {
@@ -135,13 +153,44 @@ func (d DataCollectionDetail) GetDataSetCount() int {
 	return d.DataSetCount
 }
 
+func (d DataCollectionDetail) GetForeignKeys() []ForeignKey {
+	return d.ForeignKeys
+}
+
+// GetReferencedDataCollections returns the names of the data collections referenced by foreign keys.
+// Self-references are not included and each name is returned only once.
+func (d DataCollectionDetail) GetReferencedDataCollections() []string {
+	var result []string
+	for _, fk := range d.ForeignKeys {
+		if fk.ReferencedDataCollection == d.Name {
+			continue
+		}
+
+		var found bool
+		for _, name := range result {
+			if name == fk.ReferencedDataCollection {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, fk.ReferencedDataCollection)
+		}
+	}
+	return result
+}
+
 // Connector is database connector.
-// It's used to connect to the database quickly with predefined credentials, filters, and sorts.
+// It's used to connect to the database quickly with predefined credentials, filters, sorts, queries, projections, and samples.
 type Connector struct {
 	driver Driver
 	dsn    string
+	logger *log.Logger
 	DefaultSortBuilder
 	DefaultFilterBuilder
+	DefaultQueryBuilder
+	DefaultProjectionBuilder
+	DefaultSampleBuilder
 }
 
 // Connect connects to the database.
@@ -151,16 +200,63 @@ func (c *Connector) Connect(ctx context.Context) (Connection, error) {
 		return nil, err
 	}
 
+	if lConn, ok := conn.(LoggableConnection); ok && c.logger != nil {
+		lConn.SetLogger(c.logger)
+	}
+
 	if fConn, ok := conn.(FilterableConnection); ok {
 		for dc, filters := range c.GetAllFilters() {
 			for _, filter := range filters {
-				fConn.(FilterableConnection).WhereCondition(dc, filter.GetCondition(), filter.GetKey(), filter.GetValue())
+				fConn.(FilterableConnection).WhereExpression(dc, filter.GetExpression())
 			}
 		}
 
 		for _, filter := range c.GetRootFilters() {
-			fConn.(FilterableConnection).WhereRootCondition(filter.GetCondition(), filter.GetKey(), filter.GetValue())
+			fConn.(FilterableConnection).WhereRootExpression(filter.GetExpression())
+		}
+	}
+
+	if queries := c.GetQueries(); len(queries) > 0 {
+		qConn, ok := conn.(QueryableConnection)
+		if !ok {
+			_ = conn.Close()
+			return nil, ErrConnectionNotQueryable
+		}
+		for name, query := range queries {
+			qConn.DefineQuery(name, query)
+		}
+	}
+
+	if projections := c.GetProjections(); len(projections) > 0 {
+		pConn, ok := conn.(ProjectableConnection)
+		if !ok {
+			_ = conn.Close()
+			return nil, ErrConnectionNotProjectable
 		}
+		for dc, projection := range projections {
+			if len(projection.Include) > 0 {
+				pConn.IncludeColumns(dc, projection.Include...)
+			}
+			if len(projection.Exclude) > 0 {
+				pConn.ExcludeColumns(dc, projection.Exclude...)
+			}
+		}
+	}
+
+	// the referential sampling follows the filters too, so it's set even without samples.
+	if len(c.GetSamples()) > 0 || c.GetRootSample() != nil || c.IsReferentialSampling() {
+		sConn, ok := conn.(SampleableConnection)
+		if !ok {
+			_ = conn.Close()
+			return nil, ErrConnectionNotSampleable
+		}
+		for dc, sample := range c.GetSamples() {
+			sConn.SampleDataCollection(dc, sample)
+		}
+		if c.GetRootSample() != nil {
+			sConn.SampleRoot(c.GetRootSample())
+		}
+		sConn.SetReferentialSampling(c.IsReferentialSampling())
 	}
 
 	if sConn, ok := conn.(SortableConnection); ok {
@@ -187,6 +283,11 @@ func (c *Connector) IsReadable() bool {
 	return c.driver.IsReadable()
 }
 
+// SetLogger sets the logger of the connections that implement LoggableConnection.
+func (c *Connector) SetLogger(logger *log.Logger) {
+	c.logger = logger
+}
+
 // GetDriver returns the driver.
 func (c *Connector) GetDriver() Driver {
 	return c.driver
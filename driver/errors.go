@@ -7,9 +7,21 @@ import (
 var ErrDriverNotFound = errors.New("driver not found")
 
 var (
-	ErrConnectionNotReadable = errors.New("connection is not readable")
-	ErrConnectionNotWritable = errors.New("connection not writable")
-	ErrConnectionIsClosed    = errors.New("connection is closed")
+	ErrConnectionNotReadable    = errors.New("connection is not readable")
+	ErrConnectionNotWritable    = errors.New("connection not writable")
+	ErrConnectionIsClosed       = errors.New("connection is closed")
+	ErrConnectionNotQueryable   = errors.New("connection doesn't support custom queries")
+	ErrConnectionNotProjectable = errors.New("connection doesn't support column projections")
+	ErrConnectionNotSampleable  = errors.New("connection doesn't support sampling")
 )
 
 var ErrConnectionPoolOutOfIndex = errors.New("connection pool out of index")
+
+var ErrInvalidExpression = errors.New("invalid filter expression")
+
+var ErrInvalidSample = errors.New("invalid sample")
+
+var (
+	ErrColumnNotFound     = errors.New("column not found")
+	ErrNoColumnsProjected = errors.New("no columns left after the projection")
+)
@@ -0,0 +1,149 @@
+package driver
+
+import (
+	"strings"
+)
+
+// Expression is a node of a filter expression.
+type Expression interface {
+	// Render renders the expression as SQL with the given dialect.
+	// The bind arguments of the expression are appended to args, and the result is returned.
+	Render(dialect Dialect, args []any) (string, []any)
+}
+
+// Comparison is an expression that compares the value of a key with the given values.
+// Most conditions take one value, In and NotIn take one or more values, Between and NotBetween
+// take two values, and IsNull and IsNotNull take no value.
+type Comparison struct {
+	Key       string
+	Condition Condition
+	Values    []any
+}
+
+// Compare returns a comparison of the key with the given values.
+func Compare(key string, condition Condition, values ...any) *Comparison {
+	return &Comparison{
+		Key:       key,
+		Condition: condition,
+		Values:    values,
+	}
+}
+
+func (c *Comparison) Render(dialect Dialect, args []any) (string, []any) {
+	var sql strings.Builder
+	sql.WriteString(dialect.QuoteIdentifier(c.Key))
+	sql.WriteString(" " + c.Condition.String())
+
+	switch c.Condition {
+	case IsNull, IsNotNull:
+	case In, NotIn:
+		if len(c.Values) == 0 {
+			// nothing is in an empty list.
+			if c.Condition == In {
+				return "1 = 0", args
+			}
+			return "1 = 1", args
+		}
+		sql.WriteString(" (")
+		for i, v := range c.Values {
+			if i > 0 {
+				sql.WriteString(", ")
+			}
+			var p string
+			p, args = bind(dialect, args, v)
+			sql.WriteString(p)
+		}
+		sql.WriteString(")")
+	case Between, NotBetween:
+		var low, high string
+		low, args = bind(dialect, args, c.value(0))
+		high, args = bind(dialect, args, c.value(1))
+		sql.WriteString(" " + low + " AND " + high)
+	default:
+		var p string
+		p, args = bind(dialect, args, c.value(0))
+		sql.WriteString(" " + p)
+	}
+	return sql.String(), args
+}
+
+func (c *Comparison) value(i int) any {
+	if i < len(c.Values) {
+		return c.Values[i]
+	}
+	return nil
+}
+
+// LogicalOperator is the operator of a logical expression.
+type LogicalOperator uint8
+
+const (
+	AndOperator LogicalOperator = iota // AndOperator is true when all the expressions are true.
+	OrOperator                         // OrOperator is true when any of the expressions is true.
+)
+
+var logicalOperators = map[LogicalOperator]string{
+	AndOperator: "AND",
+	OrOperator:  "OR",
+}
+
+// String returns the SQL keyword of the operator.
+func (o LogicalOperator) String() string {
+	return logicalOperators[o]
+}
+
+// Logical is an expression that combines expressions with a logical operator.
+type Logical struct {
+	Operator    LogicalOperator
+	Expressions []Expression
+}
+
+// And returns an expression that is true when all the given expressions are true.
+func And(expressions ...Expression) *Logical {
+	return &Logical{Operator: AndOperator, Expressions: expressions}
+}
+
+// Or returns an expression that is true when any of the given expressions is true.
+func Or(expressions ...Expression) *Logical {
+	return &Logical{Operator: OrOperator, Expressions: expressions}
+}
+
+func (l *Logical) Render(dialect Dialect, args []any) (string, []any) {
+	if len(l.Expressions) == 0 {
+		if l.Operator == OrOperator {
+			return "1 = 0", args
+		}
+		return "1 = 1", args
+	}
+	if len(l.Expressions) == 1 {
+		return l.Expressions[0].Render(dialect, args)
+	}
+
+	var sql strings.Builder
+	sql.WriteString("(")
+	for i, e := range l.Expressions {
+		if i > 0 {
+			sql.WriteString(" " + l.Operator.String() + " ")
+		}
+		var s string
+		s, args = e.Render(dialect, args)
+		sql.WriteString(s)
+	}
+	sql.WriteString(")")
+	return sql.String(), args
+}
+
+// Negation is an expression that is true when its expression is false.
+type Negation struct {
+	Expression Expression
+}
+
+// Not returns an expression that is true when the given expression is false.
+func Not(expression Expression) *Negation {
+	return &Negation{Expression: expression}
+}
+
+func (n *Negation) Render(dialect Dialect, args []any) (string, []any) {
+	s, args := n.Expression.Render(dialect, args)
+	return "NOT (" + s + ")", args
+}
@@ -0,0 +1,329 @@
+package driver
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParseExpression parses a filter expression, e.g.
+//
+//	status IN ('active', 'trial') AND (email LIKE '%@corp.com' OR deleted_at IS NULL)
+//
+// The comparisons are a key, an operator (=, !=, <>, >, <, >=, <=, [NOT] LIKE, [NOT] IN,
+// [NOT] BETWEEN ... AND ..., IS [NOT] NULL) and the values. The values are quoted with single
+// or double quotes, or unquoted when they don't contain spaces, commas or parentheses.
+// An unquoted NULL value is rejected, since a comparison with NULL never matches, IS [NOT] NULL
+// is used instead; a quoted 'NULL' is the string.
+// The comparisons are combined with AND, OR, NOT and parentheses, AND binds tighter than OR.
+// The keywords are case-insensitive.
+func ParseExpression(expression string) (Expression, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &expressionParser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+	return e, nil
+}
+
+type tokenKind uint8
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenizeExpression(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case r == '\'' || r == '"':
+			// a quote is escaped by doubling it.
+			var s strings.Builder
+			start := i
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("%w: unterminated string at %d", ErrInvalidExpression, start)
+				}
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						s.WriteRune(r)
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				s.WriteRune(runes[i])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s.String(), pos: start})
+		case strings.ContainsRune("=!<>", r):
+			start := i
+			for i < len(runes) && strings.ContainsRune("=!<>", runes[i]) {
+				i++
+			}
+			op := string(runes[start:i])
+			switch op {
+			case "=", "!=", "<>", ">", "<", ">=", "<=":
+			default:
+				return nil, fmt.Errorf("%w: unknown operator %q at %d", ErrInvalidExpression, op, start)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: start})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("(),'\"=!<>", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[start:i]), pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+type expressionParser struct {
+	tokens []token
+	i      int
+}
+
+func (p *expressionParser) done() bool {
+	return p.i >= len(p.tokens)
+}
+
+func (p *expressionParser) peek() token {
+	if p.done() {
+		return token{kind: tokenWord, pos: -1}
+	}
+	return p.tokens[p.i]
+}
+
+func (p *expressionParser) next() token {
+	t := p.peek()
+	p.i++
+	return t
+}
+
+// isKeyword reports whether the next token is the given keyword.
+func (p *expressionParser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return !p.done() && t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+// acceptKeyword consumes the next token if it's the given keyword.
+func (p *expressionParser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *expressionParser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.errorf("expected %s", keyword)
+	}
+	return nil
+}
+
+func (p *expressionParser) errorf(format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	if p.done() {
+		return fmt.Errorf("%w: %s at the end", ErrInvalidExpression, msg)
+	}
+	return fmt.Errorf("%w: %s at %d", ErrInvalidExpression, msg, p.peek().pos)
+}
+
+func (p *expressionParser) parseOr() (Expression, error) {
+	e, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	expressions := []Expression{e}
+	for p.acceptKeyword("OR") {
+		e, err = p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, e)
+	}
+	if len(expressions) == 1 {
+		return e, nil
+	}
+	return Or(expressions...), nil
+}
+
+func (p *expressionParser) parseAnd() (Expression, error) {
+	e, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	expressions := []Expression{e}
+	for p.acceptKeyword("AND") {
+		e, err = p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, e)
+	}
+	if len(expressions) == 1 {
+		return e, nil
+	}
+	return And(expressions...), nil
+}
+
+func (p *expressionParser) parseNot() (Expression, error) {
+	if p.acceptKeyword("NOT") {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not(e), nil
+	}
+
+	if p.peek().kind == tokenLParen && !p.done() {
+		p.i++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenRParen {
+			p.i--
+			return nil, p.errorf("expected )")
+		}
+		return e, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *expressionParser) parseComparison() (Expression, error) {
+	key := p.peek()
+	if p.done() || (key.kind != tokenWord && key.kind != tokenString) {
+		return nil, p.errorf("expected a key")
+	}
+	p.i++
+
+	if t := p.peek(); !p.done() && t.kind == tokenOperator {
+		p.i++
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return Compare(key.text, GetConditionFromString(t.text), v), nil
+	}
+
+	if p.acceptKeyword("IS") {
+		condition := IsNull
+		if p.acceptKeyword("NOT") {
+			condition = IsNotNull
+		}
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return Compare(key.text, condition), nil
+	}
+
+	negated := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("LIKE"):
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return Compare(key.text, negate(Like, NotLike, negated), v), nil
+	case p.acceptKeyword("IN"):
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return Compare(key.text, negate(In, NotIn, negated), values...), nil
+	case p.acceptKeyword("BETWEEN"):
+		from, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		to, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return Compare(key.text, negate(Between, NotBetween, negated), from, to), nil
+	}
+	return nil, p.errorf("expected an operator after %q", key.text)
+}
+
+func negate(condition, negatedCondition Condition, negated bool) Condition {
+	if negated {
+		return negatedCondition
+	}
+	return condition
+}
+
+func (p *expressionParser) parseValue() (any, error) {
+	t := p.peek()
+	if p.done() || (t.kind != tokenWord && t.kind != tokenString) {
+		return nil, p.errorf("expected a value")
+	}
+	if t.kind == tokenWord && strings.EqualFold(t.text, "NULL") {
+		return nil, p.errorf("unexpected NULL value, use IS NULL or IS NOT NULL")
+	}
+	p.i++
+	return t.text, nil
+}
+
+func (p *expressionParser) parseList() ([]any, error) {
+	if p.next().kind != tokenLParen {
+		p.i--
+		return nil, p.errorf("expected (")
+	}
+	var values []any
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		switch p.next().kind {
+		case tokenComma:
+			continue
+		case tokenRParen:
+			return values, nil
+		default:
+			p.i--
+			return nil, p.errorf("expected , or )")
+		}
+	}
+}
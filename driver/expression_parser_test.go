@@ -0,0 +1,86 @@
+package driver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantSQL    string
+		wantArgs   []any
+	}{
+		{expression: "id = 1", wantSQL: `"id" = $1`, wantArgs: []any{"1"}},
+		{expression: "id!=1", wantSQL: `"id" != $1`, wantArgs: []any{"1"}},
+		{expression: "id <> 1", wantSQL: `"id" != $1`, wantArgs: []any{"1"}},
+		{expression: "age >= 18 and age < 65", wantSQL: `("age" >= $1 AND "age" < $2)`, wantArgs: []any{"18", "65"}},
+		{expression: "email LIKE '%@corp.com'", wantSQL: `"email" LIKE $1`, wantArgs: []any{"%@corp.com"}},
+		{expression: "email not like \"%@corp.com\"", wantSQL: `"email" NOT LIKE $1`, wantArgs: []any{"%@corp.com"}},
+		{expression: "status IN ('active', trial)", wantSQL: `"status" IN ($1, $2)`, wantArgs: []any{"active", "trial"}},
+		{expression: "status NOT IN (banned)", wantSQL: `"status" NOT IN ($1)`, wantArgs: []any{"banned"}},
+		{
+			expression: "created_at BETWEEN '2023-01-01' AND '2023-12-31'",
+			wantSQL:    `"created_at" BETWEEN $1 AND $2`,
+			wantArgs:   []any{"2023-01-01", "2023-12-31"},
+		},
+		{expression: "age NOT BETWEEN 1 AND 2", wantSQL: `"age" NOT BETWEEN $1 AND $2`, wantArgs: []any{"1", "2"}},
+		{expression: "deleted_at IS NULL", wantSQL: `"deleted_at" IS NULL`},
+		{expression: "deleted_at is not null", wantSQL: `"deleted_at" IS NOT NULL`},
+		// a quoted NULL is the string.
+		{expression: "name = 'NULL'", wantSQL: `"name" = $1`, wantArgs: []any{"NULL"}},
+		{expression: "name = 'it''s'", wantSQL: `"name" = $1`, wantArgs: []any{"it's"}},
+		// AND binds tighter than OR.
+		{
+			expression: "a = 1 OR b = 2 AND c = 3",
+			wantSQL:    `("a" = $1 OR ("b" = $2 AND "c" = $3))`,
+			wantArgs:   []any{"1", "2", "3"},
+		},
+		{
+			expression: "(a = 1 OR b = 2) AND NOT c = 3",
+			wantSQL:    `(("a" = $1 OR "b" = $2) AND NOT ("c" = $3))`,
+			wantArgs:   []any{"1", "2", "3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			e, err := ParseExpression(tt.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sql, args := e.Render(testDialect{}, nil)
+			if sql != tt.wantSQL {
+				t.Errorf("Render() = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Render() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"id",
+		"id =",
+		"id == 1",
+		"id = 1 AND",
+		"(id = 1",
+		"id = 1)",
+		"name = 'unterminated",
+		"status IN active",
+		"status IN (a b)",
+		"age BETWEEN 1 2",
+		"deleted_at IS 1",
+		"id = NULL",
+		"id != null",
+		"status IN (a, NULL)",
+	}
+	for _, expression := range tests {
+		if _, err := ParseExpression(expression); !errors.Is(err, ErrInvalidExpression) {
+			t.Errorf("ParseExpression(%q) error = %v, want %v", expression, err, ErrInvalidExpression)
+		}
+	}
+}
@@ -5,26 +5,68 @@ import (
 	"strings"
 )
 
+// Dialect is the SQL dialect of a driver, used by the builders to build the queries.
+type Dialect interface {
+	// QuoteIdentifier quotes the given identifier, like a table or a column name.
+	QuoteIdentifier(name string) string
+	// Placeholder returns the placeholder of the bind argument at the given position, starting from 1.
+	Placeholder(position int) string
+}
+
+// inlineDialect is the dialect of the deprecated builders, which writes the identifiers as they are
+// and the values into the query instead of binding them.
+type inlineDialect struct{}
+
+func (inlineDialect) QuoteIdentifier(name string) string {
+	return name
+}
+
+func (inlineDialect) Placeholder(int) string {
+	return ""
+}
+
+// bind appends the value to the bind arguments and returns its placeholder,
+// or returns the value itself with the inline dialect.
+func bind(dialect Dialect, args []any, value any) (string, []any) {
+	if _, ok := dialect.(inlineDialect); ok {
+		return fmt.Sprint(value), args
+	}
+	args = append(args, value)
+	return dialect.Placeholder(len(args)), args
+}
+
 // Filter is a filter for a query.
 type Filter struct {
-	Key       string
-	Value     string
-	Condition Condition
+	Expression Expression
+}
+
+// GetExpression returns the expression of the filter.
+func (f *Filter) GetExpression() Expression {
+	return f.Expression
 }
 
-// GetCondition returns the condition of the filter.
+// GetCondition returns the condition of the filter, if it's a comparison.
 func (f *Filter) GetCondition() Condition {
-	return f.Condition
+	if c, ok := f.Expression.(*Comparison); ok {
+		return c.Condition
+	}
+	return Eq
 }
 
-// GetKey returns the key of the filter.
+// GetKey returns the key of the filter, if it's a comparison.
 func (f *Filter) GetKey() string {
-	return f.Key
+	if c, ok := f.Expression.(*Comparison); ok {
+		return c.Key
+	}
+	return ""
 }
 
-// GetValue returns the value of the filter.
+// GetValue returns the first value of the filter, if it's a comparison with a value.
 func (f *Filter) GetValue() string {
-	return f.Value
+	if c, ok := f.Expression.(*Comparison); ok && len(c.Values) > 0 {
+		return fmt.Sprint(c.Values[0])
+	}
+	return ""
 }
 
 // FilterableConnection is a connection that can be filtered.
@@ -33,6 +75,8 @@ type FilterableConnection interface {
 	WhereRoot(key, value string) FilterableConnection
 	WhereCondition(dataCollection string, condition Condition, key string, value string) FilterableConnection
 	WhereRootCondition(condition Condition, key string, value string) FilterableConnection
+	WhereExpression(dataCollection string, expression Expression) FilterableConnection
+	WhereRootExpression(expression Expression) FilterableConnection
 	GetFilters(dataCollection string) []*Filter
 	GetAllFilters() map[string][]*Filter
 	GetRootFilters() []*Filter
@@ -41,7 +85,7 @@ type FilterableConnection interface {
 	ResetRootFilters()
 }
 
-// Condition is a connection to a database.
+// Condition is the condition of a comparison.
 type Condition uint8
 
 const (
@@ -57,15 +101,39 @@ const (
 	Ge
 	// Le is the less than or equal condition.
 	Le
+	// Like is the pattern matching condition.
+	Like
+	// NotLike is the negated pattern matching condition.
+	NotLike
+	// In is the condition of being in a list of values.
+	In
+	// NotIn is the condition of not being in a list of values.
+	NotIn
+	// Between is the condition of being in an inclusive range.
+	Between
+	// NotBetween is the condition of not being in an inclusive range.
+	NotBetween
+	// IsNull is the condition of being null.
+	IsNull
+	// IsNotNull is the condition of not being null.
+	IsNotNull
 )
 
 var operators = map[Condition]string{
-	Eq: "=",
-	Ne: "!=",
-	Gt: ">",
-	Lt: "<",
-	Ge: ">=",
-	Le: "<=",
+	Eq:         "=",
+	Ne:         "!=",
+	Gt:         ">",
+	Lt:         "<",
+	Ge:         ">=",
+	Le:         "<=",
+	Like:       "LIKE",
+	NotLike:    "NOT LIKE",
+	In:         "IN",
+	NotIn:      "NOT IN",
+	Between:    "BETWEEN",
+	NotBetween: "NOT BETWEEN",
+	IsNull:     "IS NULL",
+	IsNotNull:  "IS NOT NULL",
 }
 
 func (c Condition) String() string {
@@ -73,8 +141,11 @@ func (c Condition) String() string {
 }
 
 func GetConditionFromString(condition string) Condition {
+	if condition == "<>" {
+		return Ne
+	}
 	for k, v := range operators {
-		if v == condition {
+		if strings.EqualFold(v, condition) {
 			return k
 		}
 	}
@@ -99,25 +170,29 @@ func (fb *DefaultFilterBuilder) WhereRoot(key string, value string) FilterableCo
 }
 
 func (fb *DefaultFilterBuilder) WhereCondition(dataCollection string, condition Condition, key string, value string) FilterableConnection {
+	return fb.WhereExpression(dataCollection, Compare(key, condition, value))
+}
+
+// WhereRootCondition is a general filter that is not related to any data collection. and will apply to all data collections.
+func (fb *DefaultFilterBuilder) WhereRootCondition(condition Condition, key, value string) FilterableConnection {
+	return fb.WhereRootExpression(Compare(key, condition, value))
+}
+
+// WhereExpression adds a filter expression to the data collection.
+func (fb *DefaultFilterBuilder) WhereExpression(dataCollection string, expression Expression) FilterableConnection {
 	fb.initFiltersIfIsNil() // allocate memory for the filters map if it is nil, for preventing panic.
 
 	fb.filters[dataCollection] = append(fb.filters[dataCollection], &Filter{
-		Condition: condition,
-		Key:       key,
-		Value:     value,
+		Expression: expression,
 	})
 
 	return fb
 }
 
-// WhereRootCondition is a general filter that is not related to any data collection. and will apply to all data collections.
-func (fb *DefaultFilterBuilder) WhereRootCondition(condition Condition, key, value string) FilterableConnection {
-	fb.initFiltersIfIsNil() // allocate memory for the filters map if it is nil, for preventing panic.
-
+// WhereRootExpression adds a filter expression that will apply to all data collections.
+func (fb *DefaultFilterBuilder) WhereRootExpression(expression Expression) FilterableConnection {
 	fb.rootFilters = append(fb.rootFilters, &Filter{
-		Condition: condition,
-		Key:       key,
-		Value:     value,
+		Expression: expression,
 	})
 
 	return fb
@@ -125,7 +200,10 @@ func (fb *DefaultFilterBuilder) WhereRootCondition(condition Condition, key, val
 
 func (fb *DefaultFilterBuilder) GetFilters(dataCollection string) []*Filter {
 	fb.initFiltersIfIsNil() // allocate memory for the filters map if it is nil, for preventing panic.
-	return append(fb.rootFilters, fb.filters[dataCollection]...)
+	// a new slice, so appending doesn't write into the spare capacity of the root filters.
+	filters := make([]*Filter, 0, len(fb.rootFilters)+len(fb.filters[dataCollection]))
+	filters = append(filters, fb.rootFilters...)
+	return append(filters, fb.filters[dataCollection]...)
 }
 
 // GetAllFilters returns all data collections filters without applying root filters.
@@ -152,21 +230,34 @@ func (fb *DefaultFilterBuilder) ResetAllFilters() {
 	fb.rootFilters = []*Filter{}
 }
 
+// BuildFilterSQL builds the WHERE clause of the filters, with the keys and the values as they are.
+//
+// Deprecated: Use BuildFilterClause, which quotes the keys and binds the values.
 func (fb *DefaultFilterBuilder) BuildFilterSQL(dataCollection string) string {
+	sql, _ := fb.BuildFilterClause(inlineDialect{}, dataCollection)
+	return sql
+}
+
+// BuildFilterClause builds the WHERE clause of the filters with the given dialect, the filters are ANDed.
+// The keys are quoted, and the values are returned as the bind arguments of the clause.
+func (fb *DefaultFilterBuilder) BuildFilterClause(dialect Dialect, dataCollection string) (string, []any) {
 	fb.initFiltersIfIsNil() // allocate memory for the filters map if it is nil, for preventing panic.
 
 	if len(fb.rootFilters) == 0 && len(fb.filters[dataCollection]) == 0 {
-		return ""
+		return "", nil
 	}
 	var sql strings.Builder
+	var args []any
 	sql.WriteString(" WHERE ")
-	for i, filter := range append(fb.rootFilters, fb.filters[dataCollection]...) {
+	for i, filter := range fb.GetFilters(dataCollection) {
 		if i > 0 {
 			sql.WriteString(" AND ")
 		}
-		sql.WriteString(fmt.Sprintf("%s %s %s", filter.Key, filter.Condition, filter.Value))
+		var s string
+		s, args = filter.Expression.Render(dialect, args)
+		sql.WriteString(s)
 	}
-	return sql.String()
+	return sql.String(), args
 }
 
 func (fb *DefaultFilterBuilder) initFiltersIfIsNil() {
@@ -0,0 +1,132 @@
+package driver
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// testDialect quotes like postgres and numbers the placeholders.
+type testDialect struct{}
+
+func (testDialect) QuoteIdentifier(name string) string { return `"` + name + `"` }
+
+func (testDialect) Placeholder(position int) string { return "$" + strconv.Itoa(position) }
+
+func TestGetFiltersDoesNotAliasRootFilters(t *testing.T) {
+	fb := &DefaultFilterBuilder{}
+	// the root filters have spare capacity after three appends.
+	fb.WhereRoot("a", "1")
+	fb.WhereRoot("b", "2")
+	fb.WhereRoot("c", "3")
+	fb.Where("users", "id", "1")
+	fb.Where("orders", "id", "2")
+
+	users := fb.GetFilters("users")
+	orders := fb.GetFilters("orders")
+	if len(users) != 4 || len(orders) != 4 {
+		t.Fatalf("got %d and %d filters, want 4", len(users), len(orders))
+	}
+	if users[3] == orders[3] {
+		t.Error("the filters of orders overwrote the filters of users")
+	}
+	if got := len(fb.GetRootFilters()); got != 3 {
+		t.Errorf("got %d root filters, want 3", got)
+	}
+}
+
+func TestBuildFilterClause(t *testing.T) {
+	fb := &DefaultFilterBuilder{}
+	if sql, args := fb.BuildFilterClause(testDialect{}, "users"); sql != "" || args != nil {
+		t.Errorf("BuildFilterClause() without filters = %q, %v", sql, args)
+	}
+
+	fb.WhereRootCondition(Ge, "version", "3")
+	fb.WhereExpression("users", Or(
+		Compare("status", In, "active", "pending"),
+		Not(Compare("deleted_at", IsNull)),
+	))
+	fb.WhereCondition("users", Between, "age", "18")
+	fb.WhereExpression("orders", Compare("id", Lt, "10"))
+
+	sql, args := fb.BuildFilterClause(testDialect{}, "users")
+	wantSQL := ` WHERE "version" >= $1 AND ("status" IN ($2, $3) OR NOT ("deleted_at" IS NULL)) AND "age" BETWEEN $4 AND $5`
+	if sql != wantSQL {
+		t.Errorf("BuildFilterClause() = %q, want %q", sql, wantSQL)
+	}
+	if want := []any{"3", "active", "pending", "18", nil}; !reflect.DeepEqual(args, want) {
+		t.Errorf("BuildFilterClause() args = %v, want %v", args, want)
+	}
+
+	fb.ResetFilters("users")
+	if sql, _ := fb.BuildFilterClause(testDialect{}, "users"); sql != ` WHERE "version" >= $1` {
+		t.Errorf("BuildFilterClause() after ResetFilters = %q", sql)
+	}
+	fb.ResetAllFilters()
+	if sql, _ := fb.BuildFilterClause(testDialect{}, "orders"); sql != "" {
+		t.Errorf("BuildFilterClause() after ResetAllFilters = %q", sql)
+	}
+}
+
+func TestDeprecatedFilterAndSortBuilders(t *testing.T) {
+	fb := &DefaultFilterBuilder{}
+	fb.WhereRoot("version", "3")
+	fb.WhereCondition("users", Ge, "age", "18")
+	fb.WhereExpression("users", Compare("status", In, "'active'", "'pending'"))
+
+	if got, want := fb.BuildFilterSQL("users"), " WHERE version = 3 AND age >= 18 AND status IN ('active', 'pending')"; got != want {
+		t.Errorf("BuildFilterSQL() = %q, want %q", got, want)
+	}
+
+	filters := fb.GetFilters("users")
+	if f := filters[1]; f.GetKey() != "age" || f.GetCondition() != Ge || f.GetValue() != "18" {
+		t.Errorf("filter = %q %v %q, want age >= 18", f.GetKey(), f.GetCondition(), f.GetValue())
+	}
+	nested := &Filter{Expression: Or(Compare("a", Eq, 1))}
+	if nested.GetKey() != "" || nested.GetCondition() != Eq || nested.GetValue() != "" {
+		t.Errorf("filter of a logical expression = %q %v %q, want none", nested.GetKey(), nested.GetCondition(), nested.GetValue())
+	}
+
+	sb := &DefaultSortBuilder{}
+	sb.OrderBy("users", "created_at", Desc)
+	sb.OrderBy("users", "id")
+	if got, want := sb.BuildSortSQL("users"), " ORDER BY created_at DESC, id ASC"; got != want {
+		t.Errorf("BuildSortSQL() = %q, want %q", got, want)
+	}
+	if got, want := sb.BuildSortClause(testDialect{}, "users"), ` ORDER BY "created_at" DESC, "id" ASC`; got != want {
+		t.Errorf("BuildSortClause() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderEdgeCases(t *testing.T) {
+	tests := []struct {
+		expression Expression
+		want       string
+	}{
+		{expression: Compare("id", In), want: "1 = 0"},
+		{expression: Compare("id", NotIn), want: "1 = 1"},
+		{expression: And(), want: "1 = 1"},
+		{expression: Or(), want: "1 = 0"},
+		{expression: And(Compare("id", IsNotNull)), want: `"id" IS NOT NULL`},
+		{expression: Not(And(Compare("a", Eq, 1), Compare("b", NotLike, "x%"))), want: `NOT (("a" = $1 AND "b" NOT LIKE $2))`},
+	}
+	for _, tt := range tests {
+		if got, _ := tt.expression.Render(testDialect{}, nil); got != tt.want {
+			t.Errorf("Render() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestGetConditionFromString(t *testing.T) {
+	for condition, operator := range operators {
+		if got := GetConditionFromString(operator); got != condition {
+			t.Errorf("GetConditionFromString(%q) = %v, want %v", operator, got, condition)
+		}
+	}
+	if got := GetConditionFromString("<>"); got != Ne {
+		t.Errorf("GetConditionFromString(<>) = %v, want %v", got, Ne)
+	}
+	if got := GetConditionFromString("not like"); got != NotLike {
+		t.Errorf("GetConditionFromString(not like) = %v, want %v", got, NotLike)
+	}
+}
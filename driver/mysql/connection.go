@@ -6,18 +6,49 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/mohammadv184/gloader/data"
 	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// dialect is the SQL dialect of MySQL.
+type dialect struct{}
+
+var _ driver.Dialect = dialect{}
+
+func (dialect) QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (dialect) Placeholder(int) string {
+	return "?"
+}
+
 // Connection is a connection to a MySQL database.
 type Connection struct {
 	conn     *sql.Conn
 	isClosed bool
 	config   *Config
+	logger   *log.Logger
+	// columns is the columns of the data collections by their names, in their order.
+	columns map[string][]string
+	// primaryKeys and foreignKeys are the keys of the data collections, which are fetched for sampling.
+	primaryKeys map[string][]string
+	foreignKeys map[string][]driver.ForeignKey
 	driver.DefaultFilterBuilder
 	driver.DefaultSortBuilder
+	driver.DefaultQueryBuilder
+	driver.DefaultProjectionBuilder
+	driver.DefaultSampleBuilder
+}
+
+// SetLogger sets the logger of the connection.
+func (m *Connection) SetLogger(logger *log.Logger) {
+	m.logger = logger
 }
 
 // Close closes the connection to the database.
@@ -58,6 +89,13 @@ func (m *Connection) Ping() error {
 
 // GetDetails returns the details of the database.
 func (m *Connection) GetDetails(ctx context.Context) (driver.DatabaseDetail, error) {
+	ctx, span := driver.StartSpan(ctx, "mysql.GetDetails", driver.AttributeDriver.String("mysql"))
+	details, err := m.getDetails(ctx)
+	driver.EndSpan(span, err)
+	return details, err
+}
+
+func (m *Connection) getDetails(ctx context.Context) (driver.DatabaseDetail, error) {
 	if m.isClosed {
 		return driver.DatabaseDetail{}, driver.ErrConnectionIsClosed
 	}
@@ -87,6 +125,11 @@ func (m *Connection) GetDetails(ctx context.Context) (driver.DatabaseDetail, err
 			return driver.DatabaseDetail{}, err
 		}
 
+		if _, ok := m.GetQuery(tableName); ok {
+			// the virtual data collection replaces the table with the same name.
+			continue
+		}
+
 		databaseInfo.DataCollections = append(databaseInfo.DataCollections, driver.DataCollectionDetail{
 			Name:         tableName,
 			DataMap:      new(data.Map),
@@ -95,7 +138,7 @@ func (m *Connection) GetDetails(ctx context.Context) (driver.DatabaseDetail, err
 	}
 
 	for i, table := range databaseInfo.DataCollections {
-		columns, err := m.conn.QueryContext(ctx, fmt.Sprintf("SHOW COLUMNS FROM %s", table.Name))
+		columns, err := m.conn.QueryContext(ctx, "SHOW COLUMNS FROM "+dialect{}.QuoteIdentifier(table.Name))
 		if err != nil {
 			if errors.Is(err, sql.ErrConnDone) {
 				m.isClosed = true
@@ -124,8 +167,16 @@ func (m *Connection) GetDetails(ctx context.Context) (driver.DatabaseDetail, err
 
 			databaseInfo.DataCollections[i].DataMap.Set(columnName, t, columnNullable == "YES")
 		}
+		m.setColumns(table.Name, table.DataMap.Keys())
+		if err = m.ProjectDataMap(table.Name, table.DataMap); err != nil {
+			return driver.DatabaseDetail{}, err
+		}
 
-		rows, err := m.conn.QueryContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s %s", table.Name, m.BuildFilterSQL(table.Name)))
+		var rows *sql.Rows
+		whereSQL, whereArgs, err := m.buildWhereSQL(ctx, table.Name)
+		if err == nil {
+			rows, err = m.conn.QueryContext(ctx, "SELECT COUNT(*) FROM "+dialect{}.QuoteIdentifier(table.Name)+whereSQL, whereArgs...)
+		}
 		if err != nil {
 			if errors.Is(err, sql.ErrConnDone) {
 				m.isClosed = true
@@ -144,16 +195,202 @@ func (m *Connection) GetDetails(ctx context.Context) (driver.DatabaseDetail, err
 				}
 				return driver.DatabaseDetail{}, err
 			}
+			if limit, ok := m.sampleLimit(table.Name); ok && uint64(count) > limit {
+				count = int(limit)
+			}
 			databaseInfo.DataCollections[i].DataSetCount = count
 		}
 		rows.Close()
 		columns.Close()
+
+		foreignKeys, err := m.foreignKeysOf(ctx, table.Name)
+		if err != nil {
+			return driver.DatabaseDetail{}, err
+		}
+		databaseInfo.DataCollections[i].ForeignKeys = foreignKeys
+	}
+
+	names := make([]string, 0, len(m.GetQueries()))
+	for name := range m.GetQueries() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dc, err := m.getQueryDetails(ctx, name)
+		if err != nil {
+			return driver.DatabaseDetail{}, err
+		}
+		databaseInfo.DataCollections = append(databaseInfo.DataCollections, dc)
 	}
 	return databaseInfo, nil
 }
 
+// getQueryDetails returns the details of the given virtual data collection,
+// with the columns of the query result and the number of its rows.
+func (m *Connection) getQueryDetails(ctx context.Context, name string) (driver.DataCollectionDetail, error) {
+	dc := driver.DataCollectionDetail{
+		Name:    name,
+		DataMap: new(data.Map),
+	}
+
+	columns, err := m.conn.QueryContext(ctx, "SELECT * FROM "+m.from(name)+" LIMIT 0")
+	if err != nil {
+		if errors.Is(err, sql.ErrConnDone) {
+			m.isClosed = true
+			return driver.DataCollectionDetail{}, driver.ErrConnectionIsClosed
+		}
+		return driver.DataCollectionDetail{}, fmt.Errorf("query %s: %w", name, err)
+	}
+	columnTypes, err := columns.ColumnTypes()
+	columns.Close()
+	if err != nil {
+		return driver.DataCollectionDetail{}, err
+	}
+
+	for _, c := range columnTypes {
+		t, err := GetTypeFromName(c.DatabaseTypeName())
+		if err != nil {
+			return driver.DataCollectionDetail{}, fmt.Errorf("query %s: column %s: %w", name, c.Name(), err)
+		}
+		nullable, ok := c.Nullable()
+		dc.DataMap.Set(c.Name(), t, nullable || !ok)
+	}
+	m.setColumns(name, dc.DataMap.Keys())
+	if err = m.ProjectDataMap(name, dc.DataMap); err != nil {
+		return driver.DataCollectionDetail{}, err
+	}
+
+	whereSQL, whereArgs, err := m.buildWhereSQL(ctx, name)
+	if err == nil {
+		err = m.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+m.from(name)+whereSQL, whereArgs...).Scan(&dc.DataSetCount)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrConnDone) {
+			m.isClosed = true
+			return driver.DataCollectionDetail{}, driver.ErrConnectionIsClosed
+		}
+		return driver.DataCollectionDetail{}, err
+	}
+	return dc, nil
+}
+
+// from returns the source of the given data collection in a FROM clause,
+// the quoted table name or the subquery of a virtual data collection.
+func (m *Connection) from(dataCollection string) string {
+	if query, ok := m.GetQuery(dataCollection); ok {
+		return "(" + query + ") AS " + dialect{}.QuoteIdentifier(dataCollection)
+	}
+	return dialect{}.QuoteIdentifier(dataCollection)
+}
+
+// setColumns keeps a copy of the columns of the data collection.
+func (m *Connection) setColumns(dataCollection string, columns []string) {
+	if m.columns == nil {
+		m.columns = make(map[string][]string)
+	}
+	m.columns[dataCollection] = append([]string(nil), columns...)
+}
+
+// selectColumns returns the column list of the SELECT of the data collection.
+// Only the projections which exclude columns need the columns of the data collection.
+func (m *Connection) selectColumns(ctx context.Context, dataCollection string) (string, error) {
+	p, ok := m.GetProjection(dataCollection)
+	if !ok || len(p.Include) > 0 {
+		return m.BuildColumnsSQL(dialect{}, dataCollection, nil), nil
+	}
+
+	columns, err := m.columnsOf(ctx, dataCollection)
+	if err != nil {
+		return "", err
+	}
+	return m.BuildColumnsSQL(dialect{}, dataCollection, columns), nil
+}
+
+// columnsOf returns the columns of the data collection, which are fetched once for each connection.
+func (m *Connection) columnsOf(ctx context.Context, dataCollection string) ([]string, error) {
+	if columns, ok := m.columns[dataCollection]; ok {
+		return columns, nil
+	}
+
+	rows, err := m.conn.QueryContext(ctx, "SELECT * FROM "+m.from(dataCollection)+" LIMIT 0")
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	m.setColumns(dataCollection, columns)
+	return columns, nil
+}
+
+// getForeignKeys returns the foreign keys of the given table.
+func (m *Connection) getForeignKeys(ctx context.Context, table string) ([]driver.ForeignKey, error) {
+	rows, err := m.conn.QueryContext(
+		ctx,
+		"SELECT CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME "+
+			"FROM information_schema.KEY_COLUMN_USAGE "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND REFERENCED_TABLE_NAME IS NOT NULL "+
+			"ORDER BY CONSTRAINT_NAME, ORDINAL_POSITION",
+		table,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrConnDone) {
+			m.isClosed = true
+			return nil, driver.ErrConnectionIsClosed
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []driver.ForeignKey
+	for rows.Next() {
+		var constraintName, columnName, referencedTable, referencedColumn string
+		err = rows.Scan(&constraintName, &columnName, &referencedTable, &referencedColumn)
+		if err != nil {
+			if errors.Is(err, sql.ErrConnDone) {
+				m.isClosed = true
+				return nil, driver.ErrConnectionIsClosed
+			}
+			return nil, err
+		}
+
+		if len(foreignKeys) == 0 || foreignKeys[len(foreignKeys)-1].Name != constraintName {
+			foreignKeys = append(foreignKeys, driver.ForeignKey{
+				Name:                     constraintName,
+				ReferencedDataCollection: referencedTable,
+			})
+		}
+		fk := &foreignKeys[len(foreignKeys)-1]
+		fk.Keys = append(fk.Keys, columnName)
+		fk.ReferencedKeys = append(fk.ReferencedKeys, referencedColumn)
+	}
+	return foreignKeys, rows.Err()
+}
+
 // Read reads data from the database.
 func (m *Connection) Read(ctx context.Context, dataCollection string, startOffset, endOffset uint64) (*data.Batch, error) {
+	ctx, span := driver.StartSpan(
+		ctx,
+		"mysql.Read",
+		driver.AttributeDriver.String("mysql"),
+		driver.AttributeDataCollection.String(dataCollection),
+		driver.AttributeStartOffset.Int64(int64(startOffset)),
+		driver.AttributeEndOffset.Int64(int64(endOffset)),
+	)
+	batch, err := m.read(ctx, dataCollection, startOffset, endOffset)
+	if err == nil {
+		span.SetAttributes(
+			driver.AttributeBatchRows.Int64(int64(batch.GetLength())),
+			driver.AttributeBatchBytes.Int64(int64(batch.GetSize())),
+		)
+	}
+	driver.EndSpan(span, err)
+	return batch, err
+}
+
+func (m *Connection) read(ctx context.Context, dataCollection string, startOffset, endOffset uint64) (*data.Batch, error) {
 	if m.isClosed {
 		return nil, driver.ErrConnectionIsClosed
 	}
@@ -161,17 +398,40 @@ func (m *Connection) Read(ctx context.Context, dataCollection string, startOffse
 
 	batch := data.NewDataBatch()
 
-	rows, err := m.conn.QueryContext(
-		ctx,
-		"SELECT * FROM "+
-			dataCollection+
-			m.BuildFilterSQL(dataCollection)+
-			m.BuildSortSQL(dataCollection)+
-			" LIMIT "+
-			fmt.Sprint(startOffset)+
-			", "+
-			fmt.Sprint(endOffset-startOffset),
-	)
+	if limit, ok := m.sampleLimit(dataCollection); ok {
+		// the rows after the sample aren't read.
+		if startOffset >= limit {
+			return batch, nil
+		}
+		if endOffset > limit {
+			endOffset = limit
+		}
+	}
+
+	var rows *sql.Rows
+	columnsSQL, err := m.selectColumns(ctx, dataCollection)
+	if err == nil {
+		var whereSQL, orderSQL string
+		var args []any
+		whereSQL, args, err = m.buildWhereSQL(ctx, dataCollection)
+		if err == nil {
+			orderSQL, args, err = m.buildOrderSQL(ctx, dataCollection, args)
+		}
+		if err == nil {
+			rows, err = m.conn.QueryContext(
+				ctx,
+				"SELECT "+columnsSQL+" FROM "+
+					m.from(dataCollection)+
+					whereSQL+
+					orderSQL+
+					" LIMIT "+
+					fmt.Sprint(startOffset)+
+					", "+
+					fmt.Sprint(endOffset-startOffset),
+				args...,
+			)
+		}
+	}
 	if err != nil {
 		if errors.Is(err, sql.ErrConnDone) {
 			m.isClosed = true
@@ -181,6 +441,7 @@ func (m *Connection) Read(ctx context.Context, dataCollection string, startOffse
 	}
 
 	defer rows.Close()
+	trace.SpanFromContext(ctx).AddEvent("query executed")
 
 	columns, err := rows.ColumnTypes()
 	if err != nil {
@@ -0,0 +1,41 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFrom(t *testing.T) {
+	m := &Connection{}
+	if got, want := m.from("users"), "`users`"; got != want {
+		t.Errorf("from() of a table = %q, want %q", got, want)
+	}
+
+	m.DefineQuery("totals", "SELECT user_id, SUM(amount) AS total FROM orders GROUP BY user_id;")
+	if got, want := m.from("totals"), "(SELECT user_id, SUM(amount) AS total FROM orders GROUP BY user_id) AS `totals`"; got != want {
+		t.Errorf("from() of a query = %q, want %q", got, want)
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	m := &Connection{}
+	m.IncludeColumns("users", "id", "name")
+	m.ExcludeColumns("posts", "body")
+	// the columns of posts are known, so they aren't fetched.
+	m.setColumns("posts", []string{"id", "title", "body"})
+
+	tests := map[string]string{
+		"orders": "*",
+		"users":  "`id`, `name`",
+		"posts":  "`id`, `title`",
+	}
+	for dc, want := range tests {
+		got, err := m.selectColumns(context.Background(), dc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("selectColumns(%s) = %q, want %q", dc, got, want)
+		}
+	}
+}
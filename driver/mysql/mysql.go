@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/log"
 
 	// Import the mysql driver.
 	_ "github.com/go-sql-driver/mysql"
@@ -23,8 +24,7 @@ func init() {
 		mu:    &sync.Mutex{},
 	})
 	if err != nil {
-		// TODO: logging system
-		//log.Println(err)
+		log.Error("failed to register the mysql driver", log.Err(err))
 	}
 }
 
@@ -68,5 +68,5 @@ func (m *MySQL) Open(ctx context.Context, name string) (driver.Connection, error
 		return nil, err
 	}
 
-	return &Connection{conn: conn, config: config}, nil
+	return &Connection{conn: conn, config: config, logger: log.Default()}, nil
 }
@@ -0,0 +1,257 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mohammadv184/gloader/driver"
+)
+
+// primaryKeyOf returns the primary key columns of the data collection, which are fetched once for each connection.
+// The virtual data collections have no primary key.
+func (m *Connection) primaryKeyOf(ctx context.Context, dataCollection string) ([]string, error) {
+	if _, ok := m.GetQuery(dataCollection); ok {
+		return nil, nil
+	}
+	if keys, ok := m.primaryKeys[dataCollection]; ok {
+		return keys, nil
+	}
+
+	rows, err := m.conn.QueryContext(
+		ctx,
+		"SELECT COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY' "+
+			"ORDER BY ORDINAL_POSITION",
+		dataCollection,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if m.primaryKeys == nil {
+		m.primaryKeys = make(map[string][]string)
+	}
+	m.primaryKeys[dataCollection] = keys
+	return keys, nil
+}
+
+// foreignKeysOf returns the foreign keys of the data collection, which are fetched once for each connection.
+// The virtual data collections have no foreign keys.
+func (m *Connection) foreignKeysOf(ctx context.Context, dataCollection string) ([]driver.ForeignKey, error) {
+	if _, ok := m.GetQuery(dataCollection); ok {
+		return nil, nil
+	}
+	if foreignKeys, ok := m.foreignKeys[dataCollection]; ok {
+		return foreignKeys, nil
+	}
+
+	foreignKeys, err := m.getForeignKeys(ctx, dataCollection)
+	if err != nil {
+		return nil, err
+	}
+	if m.foreignKeys == nil {
+		m.foreignKeys = make(map[string][]driver.ForeignKey)
+	}
+	m.foreignKeys[dataCollection] = foreignKeys
+	return foreignKeys, nil
+}
+
+// sampleKeysOf returns the columns which are hashed to sample the data collection,
+// its primary key or all its columns if it has none.
+func (m *Connection) sampleKeysOf(ctx context.Context, dataCollection string) ([]string, error) {
+	keys, err := m.primaryKeyOf(ctx, dataCollection)
+	if err != nil || len(keys) > 0 {
+		return keys, err
+	}
+	return m.columnsOf(ctx, dataCollection)
+}
+
+// sampleLimit returns the maximum number of rows read from the data collection, and false if it isn't limited.
+func (m *Connection) sampleLimit(dataCollection string) (uint64, bool) {
+	if sample, ok := m.GetSample(dataCollection); ok {
+		return sample.Limit()
+	}
+	return 0, false
+}
+
+// buildWhereSQL builds the WHERE clause of the filters and the sample of the data collection.
+func (m *Connection) buildWhereSQL(ctx context.Context, dataCollection string) (string, []any, error) {
+	return m.buildSampleWhereSQL(ctx, dataCollection, nil, make(map[string]bool))
+}
+
+// buildSampleWhereSQL builds the WHERE clause of the data collection, and appends its bind arguments to args.
+// The path is the data collections whose WHERE clauses are being built, which are skipped to break the
+// foreign key cycles.
+func (m *Connection) buildSampleWhereSQL(ctx context.Context, dataCollection string, args []any, path map[string]bool) (string, []any, error) {
+	var conditions []string
+
+	filterSQL, filterArgs := m.BuildFilterClause(dialect{}, dataCollection)
+	if filterSQL != "" {
+		conditions = append(conditions, strings.TrimPrefix(filterSQL, " WHERE "))
+		args = append(args, filterArgs...)
+	}
+
+	if sample, ok := m.GetSample(dataCollection); ok && sample.Method == driver.SamplePercentage {
+		keys, err := m.sampleKeysOf(ctx, dataCollection)
+		if err != nil {
+			return "", nil, err
+		}
+		var hash string
+		hash, args = hashSQL(keys, sample.Seed, args)
+		// the percentage is compared in millionths, so it can have four decimals.
+		args = append(args, int64(sample.Percentage*10000))
+		conditions = append(conditions, "MOD("+hash+", 1000000) < ?")
+	}
+
+	if m.IsReferentialSampling() {
+		path[dataCollection] = true
+		defer delete(path, dataCollection)
+
+		foreignKeys, err := m.foreignKeysOf(ctx, dataCollection)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, fk := range foreignKeys {
+			if path[fk.ReferencedDataCollection] {
+				continue
+			}
+			restricted, err := m.isRestricted(ctx, fk.ReferencedDataCollection, path)
+			if err != nil {
+				return "", nil, err
+			}
+			if !restricted {
+				continue
+			}
+
+			var condition string
+			condition, args, err = m.buildReferenceSQL(ctx, fk, args, path)
+			if err != nil {
+				return "", nil, err
+			}
+			conditions = append(conditions, condition)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", args, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// isRestricted returns true if only some rows of the data collection are read,
+// because it's filtered or sampled, or it references a restricted data collection.
+func (m *Connection) isRestricted(ctx context.Context, dataCollection string, path map[string]bool) (bool, error) {
+	if _, ok := m.GetSample(dataCollection); ok || len(m.GetFilters(dataCollection)) > 0 {
+		return true, nil
+	}
+
+	path[dataCollection] = true
+	defer delete(path, dataCollection)
+
+	foreignKeys, err := m.foreignKeysOf(ctx, dataCollection)
+	if err != nil {
+		return false, err
+	}
+	for _, fk := range foreignKeys {
+		if path[fk.ReferencedDataCollection] {
+			continue
+		}
+		restricted, err := m.isRestricted(ctx, fk.ReferencedDataCollection, path)
+		if err != nil || restricted {
+			return restricted, err
+		}
+	}
+	return false, nil
+}
+
+// buildReferenceSQL builds the condition that the foreign key references one of the read rows of its parent,
+// and appends its bind arguments to args.
+func (m *Connection) buildReferenceSQL(ctx context.Context, fk driver.ForeignKey, args []any, path map[string]bool) (string, []any, error) {
+	parent := fk.ReferencedDataCollection
+
+	var where, order string
+	var err error
+	where, args, err = m.buildSampleWhereSQL(ctx, parent, args, path)
+	if err != nil {
+		return "", nil, err
+	}
+	order, args, err = m.buildOrderSQL(ctx, parent, args)
+	if err != nil {
+		return "", nil, err
+	}
+	var limit string
+	if rows, ok := m.sampleLimit(parent); ok {
+		limit = fmt.Sprint(" LIMIT ", rows)
+	}
+
+	// the rows with a NULL key don't reference a parent.
+	nulls := make([]string, 0, len(fk.Keys))
+	for _, key := range fk.Keys {
+		nulls = append(nulls, dialect{}.QuoteIdentifier(key)+" IS NULL")
+	}
+	keys := quoteIdentifiers(fk.Keys)
+	if len(fk.Keys) > 1 {
+		keys = "(" + keys + ")"
+	}
+	referencedKeys := quoteIdentifiers(fk.ReferencedKeys)
+
+	// LIMIT isn't supported in an IN subquery, so the parent rows are selected in a derived table.
+	return "(" + strings.Join(nulls, " OR ") + " OR " + keys + " IN (" +
+		"SELECT " + referencedKeys + " FROM (" +
+		"SELECT " + referencedKeys + " FROM " + m.from(parent) + where + order + limit +
+		") AS " + dialect{}.QuoteIdentifier("gloader_"+parent) + "))", args, nil
+}
+
+// buildOrderSQL builds the ORDER BY clause of the sorts and the sample of the data collection,
+// and appends its bind arguments to args.
+// The random samples are ordered by the hash of their keys, and the first rows are ordered by
+// the primary key if there's no sort, so the same rows are picked by every query.
+func (m *Connection) buildOrderSQL(ctx context.Context, dataCollection string, args []any) (string, []any, error) {
+	sample, ok := m.GetSample(dataCollection)
+	switch {
+	case ok && sample.Method == driver.SampleRandom:
+		keys, err := m.sampleKeysOf(ctx, dataCollection)
+		if err != nil {
+			return "", nil, err
+		}
+		var hash string
+		hash, args = hashSQL(keys, sample.Seed, args)
+		return " ORDER BY " + hash + ", " + quoteIdentifiers(keys), args, nil
+	case ok && sample.Method == driver.SampleFirst && len(m.GetSorts(dataCollection)) == 0:
+		keys, err := m.primaryKeyOf(ctx, dataCollection)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(keys) > 0 {
+			return " ORDER BY " + quoteIdentifiers(keys), args, nil
+		}
+	}
+	return m.BuildSortClause(dialect{}, dataCollection), args, nil
+}
+
+// hashSQL returns an unsigned 32-bit hash of the keys and the seed, and appends the seed to args.
+func hashSQL(keys []string, seed int64, args []any) (string, []any) {
+	return "CRC32(CONCAT_WS(',', ?, " + quoteIdentifiers(keys) + "))", append(args, seed)
+}
+
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, dialect{}.QuoteIdentifier(name))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,150 @@
+package mysql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mohammadv184/gloader/driver"
+)
+
+// newSampleConnection returns a connection whose keys are known, so the SQL is built without a database.
+func newSampleConnection() *Connection {
+	return &Connection{
+		primaryKeys: map[string][]string{
+			"users":    {"id"},
+			"orders":   {"id"},
+			"items":    {"order_id", "line"},
+			"sessions": nil,
+		},
+		foreignKeys: map[string][]driver.ForeignKey{
+			// users and orders reference each other.
+			"users": {{Keys: []string{"last_order_id"}, ReferencedDataCollection: "orders", ReferencedKeys: []string{"id"}}},
+			"orders": {
+				{Keys: []string{"user_id"}, ReferencedDataCollection: "users", ReferencedKeys: []string{"id"}},
+				{Keys: []string{"parent_id"}, ReferencedDataCollection: "orders", ReferencedKeys: []string{"id"}},
+			},
+			"items":    {{Keys: []string{"order_id"}, ReferencedDataCollection: "orders", ReferencedKeys: []string{"id"}}},
+			"sessions": nil,
+		},
+		columns: map[string][]string{"sessions": {"token", "user_id"}},
+	}
+}
+
+func TestSampleSQL(t *testing.T) {
+	tests := []struct {
+		name      string
+		sample    *driver.Sample
+		table     string
+		sort      bool
+		wantWhere string
+		wantOrder string
+		wantArgs  []any
+	}{
+		{
+			name:      "percent",
+			sample:    driver.PercentageOfRows(12.5, 42),
+			table:     "users",
+			wantWhere: " WHERE MOD(CRC32(CONCAT_WS(',', ?, `id`)), 1000000) < ?",
+			wantArgs:  []any{int64(42), int64(125000)},
+		},
+		{
+			name:      "percent without a primary key",
+			sample:    driver.PercentageOfRows(1, 0),
+			table:     "sessions",
+			wantWhere: " WHERE MOD(CRC32(CONCAT_WS(',', ?, `token`, `user_id`)), 1000000) < ?",
+			wantArgs:  []any{int64(0), int64(10000)},
+		},
+		{
+			name:      "random",
+			sample:    driver.RandomRows(100, 7),
+			table:     "items",
+			wantOrder: " ORDER BY CRC32(CONCAT_WS(',', ?, `order_id`, `line`)), `order_id`, `line`",
+			wantArgs:  []any{int64(7)},
+		},
+		{
+			name:      "first",
+			sample:    driver.FirstRows(10),
+			table:     "items",
+			wantOrder: " ORDER BY `order_id`, `line`",
+		},
+		{
+			name:      "first in the sort order",
+			sample:    driver.FirstRows(10),
+			table:     "users",
+			sort:      true,
+			wantOrder: " ORDER BY `created_at` DESC",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newSampleConnection()
+			m.SampleDataCollection(tt.table, tt.sample)
+			if tt.sort {
+				m.OrderBy(tt.table, "created_at", driver.Desc)
+			}
+
+			where, args, err := m.buildWhereSQL(context.Background(), tt.table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			order, args, err := m.buildOrderSQL(context.Background(), tt.table, args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if where != tt.wantWhere {
+				t.Errorf("WHERE = %q, want %q", where, tt.wantWhere)
+			}
+			if order != tt.wantOrder {
+				t.Errorf("ORDER BY = %q, want %q", order, tt.wantOrder)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+
+			limit, ok := m.sampleLimit(tt.table)
+			if wantLimit, wantOk := tt.sample.Limit(); limit != wantLimit || ok != wantOk {
+				t.Errorf("sampleLimit() = %d, %v, want %d, %v", limit, ok, wantLimit, wantOk)
+			}
+		})
+	}
+}
+
+func TestReferentialSampleSQL(t *testing.T) {
+	m := newSampleConnection()
+	m.SampleDataCollection("users", driver.FirstRows(10))
+	m.Where("items", "line", "1")
+	m.SetReferentialSampling(true)
+
+	tests := map[string]struct {
+		where string
+		args  []any
+	}{
+		// the cycle back to orders, and the self-reference of orders, aren't followed.
+		"users": {},
+		"orders": {
+			where: " WHERE (`user_id` IS NULL OR `user_id` IN (SELECT `id` FROM (" +
+				"SELECT `id` FROM `users` ORDER BY `id` LIMIT 10) AS `gloader_users`))",
+		},
+		// items are filtered, and restricted by their orders, which are restricted by their users.
+		"items": {
+			where: " WHERE `line` = ? AND (`order_id` IS NULL OR `order_id` IN (SELECT `id` FROM (" +
+				"SELECT `id` FROM `orders` WHERE (`user_id` IS NULL OR `user_id` IN (SELECT `id` FROM (" +
+				"SELECT `id` FROM `users` ORDER BY `id` LIMIT 10) AS `gloader_users`))) AS `gloader_orders`))",
+			args: []any{"1"},
+		},
+		"sessions": {},
+	}
+	for table, tt := range tests {
+		where, args, err := m.buildWhereSQL(context.Background(), table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if where != tt.where {
+			t.Errorf("WHERE of %s = %q, want %q", table, where, tt.where)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("args of %s = %v, want %v", table, args, tt.args)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package driver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mohammadv184/gloader/data"
+)
+
+// Projection is the columns of a data collection which are read.
+// All the columns are included if Include is empty, and the Exclude columns are dropped from the included ones.
+type Projection struct {
+	Include []string
+	Exclude []string
+}
+
+// Has returns true if the column is read.
+func (p *Projection) Has(column string) bool {
+	for _, c := range p.Exclude {
+		if c == column {
+			return false
+		}
+	}
+	if len(p.Include) == 0 {
+		return true
+	}
+	for _, c := range p.Include {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// Columns returns the read columns of the given columns, in the same order.
+func (p *Projection) Columns(columns []string) []string {
+	result := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if p.Has(c) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// ProjectableConnection is a connection that reads only some columns of the data collections.
+// The data maps returned by GetDetails only have the read columns.
+type ProjectableConnection interface {
+	// IncludeColumns reads only the given columns of the data collection.
+	IncludeColumns(dataCollection string, columns ...string) ProjectableConnection
+	// ExcludeColumns doesn't read the given columns of the data collection.
+	ExcludeColumns(dataCollection string, columns ...string) ProjectableConnection
+	GetProjections() map[string]*Projection
+}
+
+// DefaultProjectionBuilder is a default implementation of ProjectableConnection.
+// That can be used as embedded struct in a driver to keep the projections,
+// and build the column list of a SELECT from them.
+type DefaultProjectionBuilder struct {
+	projections map[string]*Projection
+}
+
+func (pb *DefaultProjectionBuilder) IncludeColumns(dataCollection string, columns ...string) ProjectableConnection {
+	p := pb.projectionOf(dataCollection)
+	p.Include = append(p.Include, columns...)
+	return pb
+}
+
+func (pb *DefaultProjectionBuilder) ExcludeColumns(dataCollection string, columns ...string) ProjectableConnection {
+	p := pb.projectionOf(dataCollection)
+	p.Exclude = append(p.Exclude, columns...)
+	return pb
+}
+
+// GetProjections returns the projections by the names of their data collections.
+func (pb *DefaultProjectionBuilder) GetProjections() map[string]*Projection {
+	return pb.projections
+}
+
+// GetProjection returns the projection of the data collection,
+// and false if all the columns of the data collection are read.
+func (pb *DefaultProjectionBuilder) GetProjection(dataCollection string) (*Projection, bool) {
+	p, ok := pb.projections[dataCollection]
+	return p, ok
+}
+
+// ProjectDataMap deletes the columns of the data map which aren't read.
+// It returns an error if an included column doesn't exist, or no column is left.
+func (pb *DefaultProjectionBuilder) ProjectDataMap(dataCollection string, dataMap *data.Map) error {
+	p, ok := pb.GetProjection(dataCollection)
+	if !ok {
+		return nil
+	}
+	for _, c := range p.Include {
+		if !dataMap.Has(c) {
+			return fmt.Errorf("%w: %s.%s", ErrColumnNotFound, dataCollection, c)
+		}
+	}
+	// the keys are copied, because deleting changes them.
+	for _, c := range append([]string(nil), dataMap.Keys()...) {
+		if !p.Has(c) {
+			dataMap.Delete(c)
+		}
+	}
+	if dataMap.Len() == 0 {
+		return fmt.Errorf("%w: %s", ErrNoColumnsProjected, dataCollection)
+	}
+	return nil
+}
+
+// BuildColumnsSQL builds the column list of a SELECT for the data collection with the given dialect,
+// the read columns of the given ones, or * if all the columns are read.
+func (pb *DefaultProjectionBuilder) BuildColumnsSQL(dialect Dialect, dataCollection string, columns []string) string {
+	p, ok := pb.GetProjection(dataCollection)
+	if !ok {
+		return "*"
+	}
+	if len(p.Include) > 0 {
+		// the included columns are selected even if the given columns are unknown.
+		columns = p.Include
+	}
+	quoted := make([]string, 0, len(columns))
+	for _, c := range p.Columns(columns) {
+		quoted = append(quoted, dialect.QuoteIdentifier(c))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (pb *DefaultProjectionBuilder) projectionOf(dataCollection string) *Projection {
+	if pb.projections == nil {
+		pb.projections = make(map[string]*Projection)
+	}
+	p, ok := pb.projections[dataCollection]
+	if !ok {
+		p = &Projection{}
+		pb.projections[dataCollection] = p
+	}
+	return p
+}
@@ -0,0 +1,121 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mohammadv184/gloader/data"
+)
+
+type projectableTestConnection struct {
+	testConnection
+	DefaultProjectionBuilder
+}
+
+func TestProjectionColumns(t *testing.T) {
+	columns := []string{"id", "name", "avatar", "bio"}
+	tests := []struct {
+		name       string
+		projection Projection
+		want       []string
+	}{
+		{name: "all", want: columns},
+		// the order of the columns is kept, not the order of the projection.
+		{name: "include", projection: Projection{Include: []string{"name", "id"}}, want: []string{"id", "name"}},
+		{name: "exclude", projection: Projection{Exclude: []string{"avatar", "bio"}}, want: []string{"id", "name"}},
+		{
+			name:       "include and exclude",
+			projection: Projection{Include: []string{"id", "name", "bio"}, Exclude: []string{"bio"}},
+			want:       []string{"id", "name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.projection.Columns(columns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Columns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildColumnsSQL(t *testing.T) {
+	pb := &DefaultProjectionBuilder{}
+	pb.IncludeColumns("users", "id", "name")
+	pb.ExcludeColumns("posts", "body")
+	pb.IncludeColumns("tags", "id", "label").ExcludeColumns("tags", "label")
+
+	columns := []string{"id", "title", "body"}
+	tests := map[string]string{
+		"orders": "*",
+		// the included columns don't need the columns of the data collection.
+		"users": `"id", "name"`,
+		"posts": `"id", "title"`,
+		"tags":  `"id"`,
+	}
+	for dc, want := range tests {
+		if got := pb.BuildColumnsSQL(testDialect{}, dc, columns); got != want {
+			t.Errorf("BuildColumnsSQL(%s) = %q, want %q", dc, got, want)
+		}
+	}
+}
+
+func TestProjectDataMap(t *testing.T) {
+	newDataMap := func() *data.Map {
+		dm := &data.Map{}
+		for _, c := range []string{"id", "name", "avatar"} {
+			dm.Set(c, nil, true, true)
+		}
+		return dm
+	}
+
+	pb := &DefaultProjectionBuilder{}
+	dm := newDataMap()
+	if err := pb.ProjectDataMap("users", dm); err != nil || dm.Len() != 3 {
+		t.Errorf("ProjectDataMap() without a projection = %v, %d columns", err, dm.Len())
+	}
+
+	pb.ExcludeColumns("users", "avatar")
+	if err := pb.ProjectDataMap("users", dm); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := dm.Keys(), []string{"id", "name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("columns = %v, want %v", got, want)
+	}
+	if dm.Has("avatar") || dm.HasDefaultValue("avatar") || dm.IsNullable("avatar") {
+		t.Error("the options of the excluded column are kept")
+	}
+
+	pb.IncludeColumns("posts", "id", "title")
+	if err := pb.ProjectDataMap("posts", newDataMap()); !errors.Is(err, ErrColumnNotFound) {
+		t.Errorf("ProjectDataMap() of an unknown column error = %v, want %v", err, ErrColumnNotFound)
+	}
+	pb.ExcludeColumns("tags", "id", "name", "avatar")
+	if err := pb.ProjectDataMap("tags", newDataMap()); !errors.Is(err, ErrNoColumnsProjected) {
+		t.Errorf("ProjectDataMap() without columns error = %v, want %v", err, ErrNoColumnsProjected)
+	}
+}
+
+func TestConnectorProjectsColumns(t *testing.T) {
+	conn := &projectableTestConnection{}
+	connector := NewConnector(testDriver{open: func() Connection { return conn }}, "")
+	connector.IncludeColumns("users", "id", "name")
+	connector.ExcludeColumns("posts", "body")
+	if _, err := connector.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*Projection{
+		"users": {Include: []string{"id", "name"}},
+		"posts": {Exclude: []string{"body"}},
+	}
+	if got := conn.GetProjections(); !reflect.DeepEqual(got, want) {
+		t.Errorf("projections of the connection = %v, want %v", got, want)
+	}
+
+	connector = NewConnector(testDriver{open: func() Connection { return &testConnection{} }}, "")
+	connector.ExcludeColumns("posts", "body")
+	if _, err := connector.Connect(context.Background()); !errors.Is(err, ErrConnectionNotProjectable) {
+		t.Errorf("Connect() error = %v, want %v", err, ErrConnectionNotProjectable)
+	}
+}
@@ -0,0 +1,38 @@
+package driver
+
+import "strings"
+
+// QueryableConnection is a connection that can read virtual data collections backed by custom queries,
+// like a join or an aggregate. The virtual data collections are listed, counted and read like the other ones.
+type QueryableConnection interface {
+	// DefineQuery defines a virtual data collection with the given name, backed by the given SELECT query.
+	DefineQuery(name, query string) QueryableConnection
+	GetQueries() map[string]string
+}
+
+// DefaultQueryBuilder is a default implementation of QueryableConnection.
+// That can be used as embedded struct in a driver to keep the defined queries.
+type DefaultQueryBuilder struct {
+	queries map[string]string
+}
+
+func (qb *DefaultQueryBuilder) DefineQuery(name, query string) QueryableConnection {
+	if qb.queries == nil {
+		qb.queries = make(map[string]string)
+	}
+	// the query is used as a subquery, so it can't end with a semicolon.
+	qb.queries[name] = strings.TrimRight(strings.TrimSpace(query), "; \t\n")
+	return qb
+}
+
+// GetQueries returns the queries of the virtual data collections by their names.
+func (qb *DefaultQueryBuilder) GetQueries() map[string]string {
+	return qb.queries
+}
+
+// GetQuery returns the query of the given virtual data collection,
+// and false if the data collection isn't defined by a query.
+func (qb *DefaultQueryBuilder) GetQuery(name string) (string, bool) {
+	query, ok := qb.queries[name]
+	return query, ok
+}
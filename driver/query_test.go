@@ -0,0 +1,84 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testDriver opens the connections returned by open.
+type testDriver struct {
+	open func() Connection
+}
+
+func (testDriver) GetDriverName() string { return "test" }
+
+func (testDriver) IsWritable() bool { return false }
+
+func (testDriver) IsReadable() bool { return true }
+
+func (d testDriver) Open(context.Context, string) (Connection, error) { return d.open(), nil }
+
+type testConnection struct {
+	closed bool
+}
+
+func (c *testConnection) Close() error { c.closed = true; return nil }
+
+func (c *testConnection) IsClosed() bool { return c.closed }
+
+func (c *testConnection) Ping() error { return nil }
+
+func (c *testConnection) GetDetails(context.Context) (DatabaseDetail, error) {
+	return DatabaseDetail{}, nil
+}
+
+type queryableTestConnection struct {
+	testConnection
+	DefaultQueryBuilder
+}
+
+func TestDefineQuery(t *testing.T) {
+	qb := &DefaultQueryBuilder{}
+	if _, ok := qb.GetQuery("totals"); ok {
+		t.Error("GetQuery() of an undefined query is ok")
+	}
+
+	qb.DefineQuery("totals", "\n SELECT user_id, SUM(amount) FROM orders GROUP BY user_id; \n")
+	query, ok := qb.GetQuery("totals")
+	if want := "SELECT user_id, SUM(amount) FROM orders GROUP BY user_id"; !ok || query != want {
+		t.Errorf("GetQuery() = %q, %v, want %q, true", query, ok, want)
+	}
+}
+
+func TestConnectorDefinesQueries(t *testing.T) {
+	conn := &queryableTestConnection{}
+	connector := NewConnector(testDriver{open: func() Connection { return conn }}, "")
+	connector.DefineQuery("totals", "SELECT 1")
+	connector.DefineQuery("active", "SELECT * FROM users WHERE active")
+
+	if _, err := connector.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"totals": "SELECT 1", "active": "SELECT * FROM users WHERE active"}
+	if got := conn.GetQueries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("queries of the connection = %v, want %v", got, want)
+	}
+}
+
+func TestConnectorRejectsQueriesOfUnqueryableConnections(t *testing.T) {
+	conn := &testConnection{}
+	connector := NewConnector(testDriver{open: func() Connection { return conn }}, "")
+	if _, err := connector.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() without queries error = %v", err)
+	}
+
+	connector.DefineQuery("totals", "SELECT 1")
+	if _, err := connector.Connect(context.Background()); !errors.Is(err, ErrConnectionNotQueryable) {
+		t.Errorf("Connect() error = %v, want %v", err, ErrConnectionNotQueryable)
+	}
+	if !conn.IsClosed() {
+		t.Error("the rejected connection isn't closed")
+	}
+}
@@ -0,0 +1,198 @@
+package driver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SampleMethod is how the rows of a sample are picked.
+type SampleMethod uint8
+
+const (
+	// SampleFirst picks the first rows in the sort order, or in the primary key order if there's no sort.
+	SampleFirst SampleMethod = iota
+	// SamplePercentage picks about a percentage of the rows, by a hash of their primary key and the seed.
+	SamplePercentage
+	// SampleRandom picks a number of random rows, ordered by a hash of their primary key and the seed.
+	SampleRandom
+)
+
+var sampleMethodNames = map[SampleMethod]string{
+	SampleFirst:      "first",
+	SamplePercentage: "percent",
+	SampleRandom:     "random",
+}
+
+// String returns the name of the sample method.
+func (m SampleMethod) String() string {
+	return sampleMethodNames[m]
+}
+
+// GetSampleMethodFromString returns the sample method from its name.
+func GetSampleMethodFromString(method string) (SampleMethod, error) {
+	for k, v := range sampleMethodNames {
+		if strings.EqualFold(v, method) {
+			return k, nil
+		}
+	}
+	return SampleFirst, fmt.Errorf("%w: unknown method %s", ErrInvalidSample, method)
+}
+
+// Sample is a subset of the rows of a data collection which is read instead of all of them.
+// The samples are deterministic, so the same seed picks the same rows of the same data.
+type Sample struct {
+	Method SampleMethod
+	// Rows is the number of rows of SampleFirst and SampleRandom.
+	Rows uint64
+	// Percentage is the percentage of the rows of SamplePercentage, between 0 and 100.
+	Percentage float64
+	// Seed is the seed of SamplePercentage and SampleRandom.
+	Seed int64
+}
+
+// FirstRows returns a sample of the first rows.
+func FirstRows(rows uint64) *Sample {
+	return &Sample{Method: SampleFirst, Rows: rows}
+}
+
+// PercentageOfRows returns a sample of about the percentage of the rows, picked with the seed.
+func PercentageOfRows(percentage float64, seed int64) *Sample {
+	return &Sample{Method: SamplePercentage, Percentage: percentage, Seed: seed}
+}
+
+// RandomRows returns a sample of random rows, picked with the seed.
+func RandomRows(rows uint64, seed int64) *Sample {
+	return &Sample{Method: SampleRandom, Rows: rows, Seed: seed}
+}
+
+// Limit returns the maximum number of rows of the sample, and false if it isn't limited.
+func (s *Sample) Limit() (uint64, bool) {
+	if s.Method == SamplePercentage {
+		return 0, false
+	}
+	return s.Rows, true
+}
+
+// String returns the sample in the form parsed by ParseSample.
+func (s *Sample) String() string {
+	switch s.Method {
+	case SamplePercentage:
+		return fmt.Sprintf("%s:%s:%d", s.Method, strconv.FormatFloat(s.Percentage, 'f', -1, 64), s.Seed)
+	case SampleRandom:
+		return fmt.Sprintf("%s:%d:%d", s.Method, s.Rows, s.Seed)
+	default:
+		return fmt.Sprintf("%s:%d", s.Method, s.Rows)
+	}
+}
+
+// ParseSample parses a sample, which is one of
+//
+//	first:<rows>
+//	percent:<percentage>[:<seed>]
+//	random:<rows>[:<seed>]
+//
+// The seed is 0 by default.
+func ParseSample(sample string) (*Sample, error) {
+	parts := strings.Split(sample, ":")
+	method, err := GetSampleMethodFromString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	maxParts := 3
+	if method == SampleFirst {
+		maxParts = 2
+	}
+	if len(parts) < 2 || len(parts) > maxParts {
+		return nil, fmt.Errorf("%w: %q, expected first:<rows>, percent:<percentage>[:<seed>] or random:<rows>[:<seed>]", ErrInvalidSample, sample)
+	}
+
+	var seed int64
+	if len(parts) == 3 {
+		seed, err = strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid seed %q", ErrInvalidSample, parts[2])
+		}
+	}
+
+	if method == SamplePercentage {
+		percentage, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || percentage <= 0 || percentage > 100 {
+			return nil, fmt.Errorf("%w: invalid percentage %q, expected a number between 0 and 100", ErrInvalidSample, parts[1])
+		}
+		return PercentageOfRows(percentage, seed), nil
+	}
+
+	rows, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || rows == 0 {
+		return nil, fmt.Errorf("%w: invalid rows %q", ErrInvalidSample, parts[1])
+	}
+	if method == SampleRandom {
+		return RandomRows(rows, seed), nil
+	}
+	return FirstRows(rows), nil
+}
+
+// SampleableConnection is a connection that reads samples of the data collections.
+// The data set counts returned by GetDetails are the sizes of the samples.
+type SampleableConnection interface {
+	// SampleDataCollection reads the sample of the data collection, instead of the root sample.
+	SampleDataCollection(dataCollection string, sample *Sample) SampleableConnection
+	// SampleRoot reads the sample of all the data collections.
+	SampleRoot(sample *Sample) SampleableConnection
+	// SetReferentialSampling makes the connection read only the rows whose foreign keys reference
+	// the read rows of their parents, so the sampled children always have their parents.
+	SetReferentialSampling(referential bool) SampleableConnection
+	GetSamples() map[string]*Sample
+	GetRootSample() *Sample
+	IsReferentialSampling() bool
+}
+
+// DefaultSampleBuilder is a default implementation of SampleableConnection.
+// That can be used as embedded struct in a driver to keep the samples.
+type DefaultSampleBuilder struct {
+	samples     map[string]*Sample
+	rootSample  *Sample // root sample is a general sample that will apply to all data collections.
+	referential bool
+}
+
+func (sb *DefaultSampleBuilder) SampleDataCollection(dataCollection string, sample *Sample) SampleableConnection {
+	if sb.samples == nil {
+		sb.samples = make(map[string]*Sample)
+	}
+	sb.samples[dataCollection] = sample
+	return sb
+}
+
+func (sb *DefaultSampleBuilder) SampleRoot(sample *Sample) SampleableConnection {
+	sb.rootSample = sample
+	return sb
+}
+
+func (sb *DefaultSampleBuilder) SetReferentialSampling(referential bool) SampleableConnection {
+	sb.referential = referential
+	return sb
+}
+
+// GetSamples returns the samples of the data collections without the root sample.
+func (sb *DefaultSampleBuilder) GetSamples() map[string]*Sample {
+	return sb.samples
+}
+
+// GetRootSample returns the root sample, or nil if there's none.
+func (sb *DefaultSampleBuilder) GetRootSample() *Sample {
+	return sb.rootSample
+}
+
+func (sb *DefaultSampleBuilder) IsReferentialSampling() bool {
+	return sb.referential
+}
+
+// GetSample returns the sample of the data collection, or the root sample,
+// and false if all the rows of the data collection are read.
+func (sb *DefaultSampleBuilder) GetSample(dataCollection string) (*Sample, bool) {
+	if sample, ok := sb.samples[dataCollection]; ok {
+		return sample, true
+	}
+	return sb.rootSample, sb.rootSample != nil
+}
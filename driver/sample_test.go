@@ -0,0 +1,125 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type sampleableTestConnection struct {
+	testConnection
+	DefaultSampleBuilder
+}
+
+func TestParseSample(t *testing.T) {
+	tests := []struct {
+		sample string
+		want   *Sample
+	}{
+		{sample: "first:100", want: FirstRows(100)},
+		{sample: "FIRST:1", want: FirstRows(1)},
+		{sample: "percent:10", want: PercentageOfRows(10, 0)},
+		{sample: "percent:0.5:42", want: PercentageOfRows(0.5, 42)},
+		{sample: "percent:100:-7", want: PercentageOfRows(100, -7)},
+		{sample: "random:1000", want: RandomRows(1000, 0)},
+		{sample: "random:1000:42", want: RandomRows(1000, 42)},
+	}
+	for _, tt := range tests {
+		got, err := ParseSample(tt.sample)
+		if err != nil {
+			t.Errorf("ParseSample(%q) error = %v", tt.sample, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSample(%q) = %+v, want %+v", tt.sample, got, tt.want)
+		}
+		// the string is parsed back to the same sample.
+		if again, err := ParseSample(got.String()); err != nil || !reflect.DeepEqual(again, got) {
+			t.Errorf("ParseSample(%q) = %+v, %v, want %+v", got.String(), again, err, got)
+		}
+	}
+
+	for _, sample := range []string{
+		"",
+		"first",
+		"first:0",
+		"first:-1",
+		"first:10:42",
+		"percent:0",
+		"percent:101",
+		"percent:ten",
+		"percent:10:seed",
+		"random:10:1:2",
+		"all:10",
+	} {
+		if _, err := ParseSample(sample); !errors.Is(err, ErrInvalidSample) {
+			t.Errorf("ParseSample(%q) error = %v, want %v", sample, err, ErrInvalidSample)
+		}
+	}
+}
+
+func TestSampleLimit(t *testing.T) {
+	if rows, ok := RandomRows(10, 1).Limit(); !ok || rows != 10 {
+		t.Errorf("Limit() of random rows = %d, %v, want 10, true", rows, ok)
+	}
+	if rows, ok := FirstRows(5).Limit(); !ok || rows != 5 {
+		t.Errorf("Limit() of the first rows = %d, %v, want 5, true", rows, ok)
+	}
+	if _, ok := PercentageOfRows(10, 1).Limit(); ok {
+		t.Error("Limit() of a percentage is limited")
+	}
+}
+
+func TestGetSample(t *testing.T) {
+	sb := &DefaultSampleBuilder{}
+	if _, ok := sb.GetSample("users"); ok {
+		t.Error("GetSample() without samples is ok")
+	}
+
+	sb.SampleRoot(PercentageOfRows(10, 0))
+	sb.SampleDataCollection("users", FirstRows(100))
+	if sample, ok := sb.GetSample("users"); !ok || sample.Method != SampleFirst {
+		t.Errorf("GetSample(users) = %v, %v, want the sample of users", sample, ok)
+	}
+	if sample, ok := sb.GetSample("orders"); !ok || sample.Method != SamplePercentage {
+		t.Errorf("GetSample(orders) = %v, %v, want the root sample", sample, ok)
+	}
+}
+
+func TestConnectorSamples(t *testing.T) {
+	conn := &sampleableTestConnection{}
+	connector := NewConnector(testDriver{open: func() Connection { return conn }}, "")
+	connector.SampleDataCollection("users", FirstRows(100))
+	connector.SampleRoot(RandomRows(10, 42))
+	connector.SetReferentialSampling(true)
+	if _, err := connector.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := conn.GetSamples(); !reflect.DeepEqual(got, map[string]*Sample{"users": FirstRows(100)}) {
+		t.Errorf("samples of the connection = %v", got)
+	}
+	if got := conn.GetRootSample(); !reflect.DeepEqual(got, RandomRows(10, 42)) {
+		t.Errorf("root sample of the connection = %v", got)
+	}
+	if !conn.IsReferentialSampling() {
+		t.Error("the connection doesn't sample referentially")
+	}
+
+	// the filtered data collections are followed without samples.
+	conn = &sampleableTestConnection{}
+	connector = NewConnector(testDriver{open: func() Connection { return conn }}, "")
+	connector.SetReferentialSampling(true)
+	if _, err := connector.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !conn.IsReferentialSampling() {
+		t.Error("the connection doesn't sample referentially without samples")
+	}
+
+	connector = NewConnector(testDriver{open: func() Connection { return &testConnection{} }}, "")
+	connector.SampleRoot(FirstRows(1))
+	if _, err := connector.Connect(context.Background()); !errors.Is(err, ErrConnectionNotSampleable) {
+		t.Errorf("Connect() error = %v, want %v", err, ErrConnectionNotSampleable)
+	}
+}
@@ -125,8 +125,15 @@ func (sb *DefaultSortBuilder) ResetAllSorts() {
 	sb.rootSorts = []*Sort{}
 }
 
-// BuildSortSQL builds the sort SQL.
+// BuildSortSQL builds the ORDER BY clause of the sorts, with the keys as they are.
+//
+// Deprecated: Use BuildSortClause, which quotes the keys.
 func (sb *DefaultSortBuilder) BuildSortSQL(dataCollection string) string {
+	return sb.BuildSortClause(inlineDialect{}, dataCollection)
+}
+
+// BuildSortClause builds the ORDER BY clause of the sorts with the given dialect.
+func (sb *DefaultSortBuilder) BuildSortClause(dialect Dialect, dataCollection string) string {
 	sb.initSortsIfIsNil() // allocate memory for the sorts map if it is nil, for preventing panic.
 
 	if len(sb.rootSorts) == 0 && len(sb.sorts[dataCollection]) == 0 {
@@ -138,7 +145,7 @@ func (sb *DefaultSortBuilder) BuildSortSQL(dataCollection string) string {
 		if i > 0 {
 			sql.WriteString(", ")
 		}
-		sql.WriteString(sort.Key)
+		sql.WriteString(dialect.QuoteIdentifier(sort.Key))
 		sql.WriteString(" ")
 		sql.WriteString(sort.Direction.String())
 	}
@@ -0,0 +1,41 @@
+package driver
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/mohammadv184/gloader/driver"
+
+// Span attribute keys shared by the drivers and the loader.
+const (
+	AttributeDriver         = attribute.Key("gloader.driver")
+	AttributeDataCollection = attribute.Key("gloader.data_collection")
+	AttributeStartOffset    = attribute.Key("gloader.start_offset")
+	AttributeEndOffset      = attribute.Key("gloader.end_offset")
+	AttributeBatchRows      = attribute.Key("gloader.batch.rows")
+	AttributeBatchBytes     = attribute.Key("gloader.batch.bytes")
+	AttributeRetryCount     = attribute.Key("gloader.retry.count")
+)
+
+// StartSpan starts a span as a child of the span in the given context.
+// The span is created by the tracer provider of the parent span, so drivers are traced
+// with the provider of the loader, and are not traced when there is no parent span.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).
+		TracerProvider().
+		Tracer(tracerName).
+		Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records the given error on the span, if any, and ends the span.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
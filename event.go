@@ -0,0 +1,153 @@
+package gloader
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultProgressInterval is the default interval of the table progress events.
+const DefaultProgressInterval = time.Second
+
+var ErrInvalidProgressInterval = errors.New("progress interval must be positive")
+
+// EventType is the type of migration event.
+type EventType string
+
+const (
+	// EventRunStarted is emitted when the data collections to load are resolved.
+	EventRunStarted EventType = "run_started"
+	// EventTableStarted is emitted when a data collection starts loading.
+	EventTableStarted EventType = "table_started"
+	// EventTableProgress is emitted periodically while a data collection is loading.
+	EventTableProgress EventType = "table_progress"
+	// EventTableCompleted is emitted when a data collection is loaded.
+	EventTableCompleted EventType = "table_completed"
+	// EventTableFailed is emitted when loading a data collection fails.
+	EventTableFailed EventType = "table_failed"
+	// EventRunFinished is emitted when the migration finishes, with the summary of the run.
+	EventRunFinished EventType = "run_finished"
+)
+
+// Event is a migration event. The fields that don't apply to the event type are empty.
+type Event struct {
+	Type           EventType `json:"type"`
+	Time           time.Time `json:"time"`
+	DataCollection string    `json:"table,omitempty"`
+	// DataCollections is the number of data collections of the run.
+	DataCollections int `json:"tables,omitempty"`
+	// Rows and Bytes are the rows and bytes written to the destination so far.
+	Rows  uint64 `json:"rows,omitempty"`
+	Bytes uint64 `json:"bytes,omitempty"`
+	// TotalRows is the number of rows to load.
+	TotalRows uint64 `json:"total_rows,omitempty"`
+	// RowsPerSecond is the rate of the written rows since the previous progress event.
+	RowsPerSecond float64 `json:"rows_per_second,omitempty"`
+	// Elapsed is the time since the data collection or the run started, in seconds.
+	Elapsed float64     `json:"elapsed_seconds,omitempty"`
+	Summary *RunSummary `json:"summary,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Err     error       `json:"-"`
+}
+
+// RunSummary is the summary of a migration run.
+type RunSummary struct {
+	DataCollections int `json:"tables"`
+	Completed       int `json:"completed"`
+	Failed          int `json:"failed"`
+	// Skipped is the number of empty data collections, which aren't loaded.
+	Skipped int     `json:"skipped"`
+	Rows    uint64  `json:"rows"`
+	Bytes   uint64  `json:"bytes"`
+	Elapsed float64 `json:"elapsed_seconds"`
+}
+
+// EventListener is an interface that can be implemented to receive the migration events.
+// The events of different data collections are emitted concurrently,
+// so the listener must be safe for concurrent use.
+type EventListener interface {
+	HandleEvent(e Event)
+}
+
+// EventListenerFunc is an adapter to use a function as an EventListener.
+type EventListenerFunc func(e Event)
+
+func (f EventListenerFunc) HandleEvent(e Event) {
+	f(e)
+}
+
+// JSONEventWriter is an EventListener that writes each event as a JSON line.
+type JSONEventWriter struct {
+	encoder *json.Encoder
+	mu      *sync.Mutex
+}
+
+var _ EventListener = &JSONEventWriter{}
+
+// NewJSONEventWriter returns a new JSONEventWriter that writes to w.
+func NewJSONEventWriter(w io.Writer) *JSONEventWriter {
+	return &JSONEventWriter{
+		encoder: json.NewEncoder(w),
+		mu:      &sync.Mutex{},
+	}
+}
+
+func (j *JSONEventWriter) HandleEvent(e Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	// the events are best effort, a failed write must not stop the migration.
+	_ = j.encoder.Encode(e)
+}
+
+// emit sends the event to the listeners.
+func (g *GLoader) emit(e Event) {
+	if len(g.eventListeners) == 0 {
+		return
+	}
+
+	e.Time = time.Now()
+	if e.Err != nil {
+		e.Error = e.Err.Error()
+	}
+	for _, l := range g.eventListeners {
+		l.HandleEvent(e)
+	}
+}
+
+// tableProgress returns the rows and bytes written to the given data collection so far.
+func (g *GLoader) tableProgress(dataCollection string) (rows, bytes uint64) {
+	rows = uint64(g.stats.MustGetSequentialCounter(MetricWriterTotalWrittenRows.String()).Value(dataCollection))
+	bytes = uint64(g.stats.MustGetSequentialCounter(MetricWriterTotalWrittenBytes.String()).Value(dataCollection))
+	return rows, bytes
+}
+
+// reportProgress emits the progress events of the given data collection every progress interval,
+// until the done channel is closed.
+func (g *GLoader) reportProgress(dataCollection string, totalRows uint64, startedAt time.Time, done <-chan struct{}) {
+	ticker := time.NewTicker(g.progressInterval)
+	defer ticker.Stop()
+
+	lastRows, _ := g.tableProgress(dataCollection)
+	lastReportT := startedAt
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			rows, bytes := g.tableProgress(dataCollection)
+			g.emit(Event{
+				Type:           EventTableProgress,
+				DataCollection: dataCollection,
+				Rows:           rows,
+				Bytes:          bytes,
+				TotalRows:      totalRows,
+				RowsPerSecond:  float64(rows-lastRows) / now.Sub(lastReportT).Seconds(),
+				Elapsed:        now.Sub(startedAt).Seconds(),
+			})
+			lastRows = rows
+			lastReportT = now
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package gloader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mohammadv184/gloader/data"
+)
+
+// eventRecorder is an EventListener that records the events.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) HandleEvent(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// of returns the recorded events of the given type.
+func (r *eventRecorder) of(t EventType) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []Event
+	for _, e := range r.events {
+		if e.Type == t {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func TestEventsOfASuccessfulRun(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "orders", 30)
+	src.addDataCollection(t, "users", 20)
+	src.addDataCollection(t, "empty", 0)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "orders", 0)
+	dest.addDataCollection(t, "users", 0)
+	dest.addDataCollection(t, "empty", 0)
+
+	recorder := &eventRecorder{}
+	g := newTestGLoader(t, src, dest).AddEventListener(recorder)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := recorder.of(EventRunStarted); len(got) != 1 || got[0].DataCollections != 3 {
+		t.Errorf("run_started events = %+v, want one with 3 tables", got)
+	}
+	if got := len(recorder.of(EventTableStarted)); got != 2 {
+		t.Errorf("got %d table_started events, want 2", got)
+	}
+	completed := make(map[string]uint64)
+	for _, e := range recorder.of(EventTableCompleted) {
+		completed[e.DataCollection] = e.Rows
+	}
+	if completed["orders"] != 30 || completed["users"] != 20 || len(completed) != 2 {
+		t.Errorf("table_completed rows = %v, want orders:30 users:20", completed)
+	}
+
+	finished := recorder.of(EventRunFinished)
+	if len(finished) != 1 {
+		t.Fatalf("got %d run_finished events, want 1", len(finished))
+	}
+	want := RunSummary{DataCollections: 3, Completed: 2, Skipped: 1, Rows: 50}
+	got := *finished[0].Summary
+	got.Bytes, got.Elapsed = 0, 0
+	if got != want || finished[0].Err != nil {
+		t.Errorf("run_finished summary = %+v, error %v, want %+v", got, finished[0].Err, want)
+	}
+}
+
+func TestWriteErrorFailsTheTable(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "orders", 30)
+	src.addDataCollection(t, "users", 20)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "orders", 0)
+	dest.addDataCollection(t, "users", 0)
+	errDuplicate := errors.New("duplicate key")
+	dest.writeErr = func(dataCollection string, _ *data.Batch) error {
+		if dataCollection == "orders" {
+			return errDuplicate
+		}
+		return nil
+	}
+
+	recorder := &eventRecorder{}
+	g := newTestGLoader(t, src, dest).AddEventListener(recorder)
+	err := g.Start()
+	if !errors.Is(err, errDuplicate) {
+		t.Fatalf("Start() error = %v, want %v", err, errDuplicate)
+	}
+
+	failed := recorder.of(EventTableFailed)
+	if len(failed) != 1 || failed[0].DataCollection != "orders" || !errors.Is(failed[0].Err, errDuplicate) {
+		t.Errorf("table_failed events = %+v, want one of orders with %v", failed, errDuplicate)
+	}
+	// the other data collections are still loaded.
+	if completed := recorder.of(EventTableCompleted); len(completed) != 1 || completed[0].DataCollection != "users" {
+		t.Errorf("table_completed events = %+v, want one of users", completed)
+	}
+	wantIDs(t, dest.ids("users"), 20)
+
+	finished := recorder.of(EventRunFinished)
+	if len(finished) != 1 || !errors.Is(finished[0].Err, errDuplicate) {
+		t.Fatalf("run_finished events = %+v, want one with %v", finished, errDuplicate)
+	}
+	if s := finished[0].Summary; s.Completed != 1 || s.Failed != 1 {
+		t.Errorf("run_finished summary = %+v, want 1 completed and 1 failed", s)
+	}
+}
+
+func TestReadErrorFailsTheTable(t *testing.T) {
+	src, dest := newHooksTestDatabases(t)
+	spillDir := t.TempDir()
+
+	recorder := &eventRecorder{}
+	h := &recordingHooks{}
+	g := newTestGLoader(t, src, dest).
+		AddEventListener(recorder).
+		AddHooks(h).
+		SetSpill(spillDir, 0).
+		// the start offset is beyond the 30 rows of orders.
+		SetStartOffset("orders", 50)
+	err := g.Start()
+	if !errors.Is(err, ErrEndOffsetLessThanStartOffset) {
+		t.Fatalf("Start() error = %v, want %v", err, ErrEndOffsetLessThanStartOffset)
+	}
+
+	failed := recorder.of(EventTableFailed)
+	if len(failed) != 1 || failed[0].DataCollection != "orders" || !errors.Is(failed[0].Err, ErrEndOffsetLessThanStartOffset) {
+		t.Errorf("table_failed events = %+v, want one of orders with %v", failed, ErrEndOffsetLessThanStartOffset)
+	}
+	if !errors.Is(h.errs["orders"], ErrEndOffsetLessThanStartOffset) || len(h.errs) != 1 {
+		t.Errorf("OnError calls = %v, want one of orders with %v", h.errs, ErrEndOffsetLessThanStartOffset)
+	}
+	// the other data collections are still loaded.
+	wantIDs(t, dest.ids("users"), 20)
+
+	if entries, err := os.ReadDir(spillDir); err != nil || len(entries) != 0 {
+		t.Errorf("spill directory entries = %v, %v, want none", entries, err)
+	}
+}
+
+func TestProgressEvents(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 20)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 0)
+	dest.writeErr = func(string, *data.Batch) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+
+	recorder := &eventRecorder{}
+	g := newTestGLoader(t, src, dest).
+		AddEventListener(recorder).
+		SetProgressInterval(2 * time.Millisecond).
+		SetRowsPerBatch(1).
+		SetWorkers(1)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	progress := recorder.of(EventTableProgress)
+	if len(progress) == 0 {
+		t.Fatal("no table_progress events")
+	}
+	var last uint64
+	for _, e := range progress {
+		if e.DataCollection != "users" || e.TotalRows != 20 || e.Rows < last || e.Rows > 20 {
+			t.Errorf("unexpected table_progress event %+v after %d rows", e, last)
+		}
+		last = e.Rows
+	}
+}
+
+func TestInvalidProgressInterval(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	dest := newMemoryDatabase(t, "dest")
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		recorder := &eventRecorder{}
+		err := newTestGLoader(t, src, dest).AddEventListener(recorder).SetProgressInterval(interval).Start()
+		if !errors.Is(err, ErrInvalidProgressInterval) {
+			t.Errorf("Start() with a progress interval of %v error = %v, want %v", interval, err, ErrInvalidProgressInterval)
+		}
+		if finished := recorder.of(EventRunFinished); len(finished) != 1 || !errors.Is(finished[0].Err, ErrInvalidProgressInterval) {
+			t.Errorf("run_finished events = %+v, want one with %v", finished, ErrInvalidProgressInterval)
+		}
+	}
+}
+
+func TestJSONEventWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewJSONEventWriter(&buf)
+	w.HandleEvent(Event{Type: EventTableFailed, DataCollection: "users", Rows: 10, Error: "duplicate key"})
+	w.HandleEvent(Event{Type: EventRunFinished, Summary: &RunSummary{DataCollections: 1, Failed: 1}})
+
+	var events []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e map[string]any
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d JSON lines, want 2", len(events))
+	}
+	if events[0]["type"] != "table_failed" || events[0]["table"] != "users" || events[0]["rows"] != 10.0 || events[0]["error"] != "duplicate key" {
+		t.Errorf("table_failed line = %v", events[0])
+	}
+	if _, ok := events[0]["summary"]; ok {
+		t.Errorf("table_failed line has a summary: %v", events[0])
+	}
+	if summary, ok := events[1]["summary"].(map[string]any); !ok || summary["failed"] != 1.0 {
+		t.Errorf("run_finished line = %v, want a summary with 1 failed table", events[1])
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mohammadv184/gloader/data"
 	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/log"
 	"github.com/mohammadv184/gloader/pkg/stats"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -30,28 +34,64 @@ var (
 var ErrCCCauseStopFuncCalled = errors.New("stop func called")
 
 type GLoader struct {
-	srcConnector              *driver.Connector
-	destConnector             *driver.Connector
-	reader                    *Reader
-	writer                    *Writer
-	dataCollectionEndOffset   map[string]uint64
-	dataCollectionStartOffset map[string]uint64
-	includedDataCollections   []string
-	excludedDataCollections   []string
-	rowsPerBatch              uint64
-	workers                   uint
-	ctx                       context.Context
-	ctxCancelFunc             context.CancelCauseFunc
-	stats                     *stats.Stats
+	srcConnector               *driver.Connector
+	destConnector              *driver.Connector
+	reader                     *Reader
+	writer                     *Writer
+	dataCollectionEndOffset    map[string]uint64
+	dataCollectionStartOffset  map[string]uint64
+	dataCollectionRowsPerBatch map[string]uint64
+	dataCollectionRWorkers     map[string]uint
+	dataCollectionWWorkers     map[string]uint
+	destDataCollections        map[string]string
+	columnMappings             map[string]map[string]string
+	dataCollectionLoadStrategy map[string]LoadStrategy
+	dataCollectionStaging      map[string]bool
+	includedDataCollections    []string
+	excludedDataCollections    []string
+	rowsPerBatch               uint64
+	workers                    uint
+	foreignKeyMode             ForeignKeyMode
+	loadStrategy               LoadStrategy
+	staging                    bool
+	stagingVerification        bool
+	maxDataCollections         uint
+	maxConnections             uint
+	spillEnabled               bool
+	spillDir                   string
+	spillQuota                 uint64
+	adaptiveBatchSize          bool
+	batchTargetBytes           uint64
+	batchTargetLatency         time.Duration
+	rateLimiters               *rateLimiters
+	tracerProvider             trace.TracerProvider
+	eventListeners             []EventListener
+	hooks                      []Hooks
+	hookErrorPolicy            HookErrorPolicy
+	progressInterval           time.Duration
+	logger                     *log.Logger
+	ctx                        context.Context
+	ctxCancelFunc              context.CancelCauseFunc
+	stats                      *stats.Stats
 }
 
 func NewGLoader() *GLoader {
 	return &GLoader{
-		rowsPerBatch:              DefaultRowsPerBatch,
-		workers:                   DefaultWorkers,
-		dataCollectionEndOffset:   make(map[string]uint64),
-		dataCollectionStartOffset: make(map[string]uint64),
-		stats:                     NewStats(),
+		rowsPerBatch:               DefaultRowsPerBatch,
+		workers:                    DefaultWorkers,
+		dataCollectionEndOffset:    make(map[string]uint64),
+		dataCollectionStartOffset:  make(map[string]uint64),
+		dataCollectionRowsPerBatch: make(map[string]uint64),
+		dataCollectionRWorkers:     make(map[string]uint),
+		dataCollectionWWorkers:     make(map[string]uint),
+		destDataCollections:        make(map[string]string),
+		columnMappings:             make(map[string]map[string]string),
+		dataCollectionLoadStrategy: make(map[string]LoadStrategy),
+		dataCollectionStaging:      make(map[string]bool),
+		stats:                      NewStats(),
+		rateLimiters:               newRateLimiters(),
+		progressInterval:           DefaultProgressInterval,
+		logger:                     log.Default(),
 	}
 }
 
@@ -99,6 +139,16 @@ func (g *GLoader) GetSrcDetails(ctx context.Context) (driver.DatabaseDetail, err
 	return conn.GetDetails(ctx)
 }
 
+// GetSrcDataCollections returns the source data collections which are selected by Include and Exclude.
+func (g *GLoader) GetSrcDataCollections(ctx context.Context) ([]driver.DataCollectionDetail, error) {
+	sDetails, err := g.GetSrcDetails(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dcs, _, err := SelectDataCollections(sDetails.DataCollections, g.includedDataCollections, g.excludedDataCollections)
+	return dcs, err
+}
+
 func (g *GLoader) GetDestDetails(ctx context.Context) (driver.DatabaseDetail, error) {
 	if g.destConnector == nil {
 		return driver.DatabaseDetail{}, ErrDestConnectionIsRequired
@@ -120,6 +170,74 @@ func (g *GLoader) Filter(dataCollection, key string, condition driver.Condition,
 	return g
 }
 
+// FilterExpression filters the data collection with the given expression, e.g. one parsed by driver.ParseExpression.
+func (g *GLoader) FilterExpression(dataCollection string, expression driver.Expression) *GLoader {
+	if g.srcConnector == nil {
+		panic(ErrSrcConnectionIsRequired)
+	}
+	g.srcConnector.WhereExpression(dataCollection, expression)
+	return g
+}
+
+// Query defines a virtual source data collection with the given name, backed by the given SELECT query.
+// It's loaded into the destination data collection with the same name, like a table.
+func (g *GLoader) Query(name, query string) *GLoader {
+	if g.srcConnector == nil {
+		panic(ErrSrcConnectionIsRequired)
+	}
+	g.srcConnector.DefineQuery(name, query)
+	return g
+}
+
+// IncludeColumns reads only the given columns of the data collection.
+// The projection is pushed down to the source, so the other columns aren't transferred.
+func (g *GLoader) IncludeColumns(dataCollection string, columns ...string) *GLoader {
+	if g.srcConnector == nil {
+		panic(ErrSrcConnectionIsRequired)
+	}
+	g.srcConnector.IncludeColumns(dataCollection, columns...)
+	return g
+}
+
+// ExcludeColumns doesn't read the given columns of the data collection.
+// The projection is pushed down to the source, so the columns aren't transferred.
+func (g *GLoader) ExcludeColumns(dataCollection string, columns ...string) *GLoader {
+	if g.srcConnector == nil {
+		panic(ErrSrcConnectionIsRequired)
+	}
+	g.srcConnector.ExcludeColumns(dataCollection, columns...)
+	return g
+}
+
+// Sample reads only a sample of the data collection, instead of the one of SampleAll.
+// The sample is pushed down to the source, see driver.Sample.
+func (g *GLoader) Sample(dataCollection string, sample *driver.Sample) *GLoader {
+	if g.srcConnector == nil {
+		panic(ErrSrcConnectionIsRequired)
+	}
+	g.srcConnector.SampleDataCollection(dataCollection, sample)
+	return g
+}
+
+// SampleAll reads only a sample of all the data collections.
+func (g *GLoader) SampleAll(sample *driver.Sample) *GLoader {
+	if g.srcConnector == nil {
+		panic(ErrSrcConnectionIsRequired)
+	}
+	g.srcConnector.SampleRoot(sample)
+	return g
+}
+
+// SetReferentialSampling reads only the rows whose foreign keys reference the read rows of their parents,
+// so the sampled or filtered parents of the loaded rows are loaded too.
+func (g *GLoader) SetReferentialSampling(referential bool) *GLoader {
+	if g.srcConnector == nil {
+		panic(ErrSrcConnectionIsRequired)
+	}
+	g.srcConnector.SetReferentialSampling(referential)
+	return g
+}
+
 func (g *GLoader) OrderBy(dataCollection, key string, direction driver.Direction) *GLoader {
 	if g.srcConnector == nil {
 		panic(ErrSrcConnectionIsRequired)
@@ -136,6 +254,15 @@ func (g *GLoader) FilterAll(key string, condition driver.Condition, value string
 	return g
 }
 
+// FilterAllExpression filters all the data collections with the given expression.
+func (g *GLoader) FilterAllExpression(expression driver.Expression) *GLoader {
+	if g.srcConnector == nil {
+		panic(ErrSrcConnectionIsRequired)
+	}
+	g.srcConnector.WhereRootExpression(expression)
+	return g
+}
+
 func (g *GLoader) OrderByAll(key string, direction driver.Direction) *GLoader {
 	if g.srcConnector == nil {
 		panic(ErrSrcConnectionIsRequired)
@@ -144,13 +271,17 @@ func (g *GLoader) OrderByAll(key string, direction driver.Direction) *GLoader {
 	return g
 }
 
-func (g *GLoader) Include(dataCollections ...string) *GLoader {
-	g.includedDataCollections = dataCollections
+// Include loads only the data collections which match any of the patterns,
+// the patterns are exact names, globs or regular expressions (see DataCollectionPattern).
+func (g *GLoader) Include(patterns ...string) *GLoader {
+	g.includedDataCollections = patterns
 	return g
 }
 
-func (g *GLoader) Exclude(dataCollections ...string) *GLoader {
-	g.excludedDataCollections = dataCollections
+// Exclude doesn't load the data collections which match any of the patterns, even if they're included,
+// the patterns are exact names, globs or regular expressions (see DataCollectionPattern).
+func (g *GLoader) Exclude(patterns ...string) *GLoader {
+	g.excludedDataCollections = patterns
 	return g
 }
 
@@ -164,6 +295,16 @@ func (g *GLoader) SetStartOffset(dataCollection string, offset uint64) *GLoader
 	return g
 }
 
+// readRangeOf returns the offsets of the data sets of the data collection which are read,
+// the end offset is the count of its data sets unless it's set.
+func (g *GLoader) readRangeOf(dc driver.DataCollectionDetail) (uint64, uint64) {
+	endOffset, ok := g.dataCollectionEndOffset[dc.Name]
+	if !ok {
+		endOffset = uint64(dc.DataSetCount)
+	}
+	return g.dataCollectionStartOffset[dc.Name], endOffset
+}
+
 func (g *GLoader) SetRowsPerBatch(rowsPerBatch uint64) *GLoader {
 	g.rowsPerBatch = rowsPerBatch
 	return g
@@ -174,6 +315,178 @@ func (g *GLoader) SetWorkers(workers uint) *GLoader {
 	return g
 }
 
+// SetDataCollectionRowsPerBatch sets the rows per batch of the data collection, instead of the global one.
+func (g *GLoader) SetDataCollectionRowsPerBatch(dataCollection string, rowsPerBatch uint64) *GLoader {
+	g.dataCollectionRowsPerBatch[dataCollection] = rowsPerBatch
+	return g
+}
+
+// SetDataCollectionWorkers sets the reader and writer workers of the data collection, instead of the global one.
+func (g *GLoader) SetDataCollectionWorkers(dataCollection string, workers uint) *GLoader {
+	return g.SetDataCollectionReaderWorkers(dataCollection, workers).SetDataCollectionWriterWorkers(dataCollection, workers)
+}
+
+// SetDataCollectionReaderWorkers sets the reader workers of the data collection, instead of the global one.
+func (g *GLoader) SetDataCollectionReaderWorkers(dataCollection string, workers uint) *GLoader {
+	g.dataCollectionRWorkers[dataCollection] = workers
+	return g
+}
+
+// SetDataCollectionWriterWorkers sets the writer workers of the data collection, instead of the global one.
+func (g *GLoader) SetDataCollectionWriterWorkers(dataCollection string, workers uint) *GLoader {
+	g.dataCollectionWWorkers[dataCollection] = workers
+	return g
+}
+
+// SetDestDataCollection loads the data collection into the destination data collection with the given name,
+// instead of the one with the same name.
+func (g *GLoader) SetDestDataCollection(dataCollection, destDataCollection string) *GLoader {
+	g.destDataCollections[dataCollection] = destDataCollection
+	return g
+}
+
+// SetColumnMapping loads the columns of the data collection into the destination columns with the mapped names,
+// the other columns are loaded into the columns with the same names.
+func (g *GLoader) SetColumnMapping(dataCollection string, mapping map[string]string) *GLoader {
+	g.columnMappings[dataCollection] = mapping
+	return g
+}
+
+// rowsPerBatchOf returns the rows per batch of the given data collection.
+func (g *GLoader) rowsPerBatchOf(dataCollection string) uint64 {
+	if rowsPerBatch, ok := g.dataCollectionRowsPerBatch[dataCollection]; ok {
+		return rowsPerBatch
+	}
+	return g.rowsPerBatch
+}
+
+// readerWorkersOf returns the reader workers of the given data collection.
+func (g *GLoader) readerWorkersOf(dataCollection string) uint {
+	if workers, ok := g.dataCollectionRWorkers[dataCollection]; ok {
+		return workers
+	}
+	return g.workers
+}
+
+// writerWorkersOf returns the writer workers of the given data collection.
+func (g *GLoader) writerWorkersOf(dataCollection string) uint {
+	if workers, ok := g.dataCollectionWWorkers[dataCollection]; ok {
+		return workers
+	}
+	return g.workers
+}
+
+// destDataCollectionOf returns the name of the destination data collection of the given data collection.
+func (g *GLoader) destDataCollectionOf(dataCollection string) string {
+	if dest, ok := g.destDataCollections[dataCollection]; ok {
+		return dest
+	}
+	return dataCollection
+}
+
+// SetForeignKeyMode sets how foreign key relationships between data collections are handled.
+// The default mode is ForeignKeyOrder.
+func (g *GLoader) SetForeignKeyMode(mode ForeignKeyMode) *GLoader {
+	g.foreignKeyMode = mode
+	return g
+}
+
+// SetMaxConcurrentDataCollections sets the maximum number of data collections that are loaded at the same time.
+// The remaining data collections are queued, largest first. Zero means unlimited.
+func (g *GLoader) SetMaxConcurrentDataCollections(maxDataCollections uint) *GLoader {
+	g.maxDataCollections = maxDataCollections
+	return g
+}
+
+// SetMaxConnections sets the maximum number of source and destination connections
+// that are held by the data collections loaded at the same time. Zero means unlimited.
+func (g *GLoader) SetMaxConnections(maxConnections uint) *GLoader {
+	g.maxConnections = maxConnections
+	return g
+}
+
+// SetSpill makes the buffers spill the overflowing data sets to temporary files inside the given directory,
+// instead of blocking the readers when the destination is slower than the source.
+// If the dir is empty, the default directory for temporary files is used.
+// The quota is the maximum number of bytes spilled by all data collections together. Zero means unlimited.
+func (g *GLoader) SetSpill(dir string, quota uint64) *GLoader {
+	g.spillEnabled = true
+	g.spillDir = dir
+	g.spillQuota = quota
+	return g
+}
+
+// SetAdaptiveBatchSize makes the readers and writers tune the number of rows per batch at runtime,
+// starting from the rows per batch, to reach the given size in bytes and latency per batch.
+// A zero target is ignored.
+func (g *GLoader) SetAdaptiveBatchSize(targetBytes uint64, targetLatency time.Duration) *GLoader {
+	g.adaptiveBatchSize = true
+	g.batchTargetBytes = targetBytes
+	g.batchTargetLatency = targetLatency
+	return g
+}
+
+// SetRateLimit sets the global rate limit per second of the given kind, shared by all data collections.
+// Zero means unlimited. It can be changed while the data collections are loaded.
+func (g *GLoader) SetRateLimit(kind RateLimitKind, rate float64) *GLoader {
+	g.rateLimiters.get("", kind).SetRate(rate)
+	return g
+}
+
+// SetDataCollectionRateLimit sets the rate limit per second of the given kind for a data collection.
+// It applies in addition to the global rate limit. Zero means unlimited.
+// It can be changed while the data collection is loaded.
+func (g *GLoader) SetDataCollectionRateLimit(dataCollection string, kind RateLimitKind, rate float64) *GLoader {
+	g.rateLimiters.get(dataCollection, kind).SetRate(rate)
+	return g
+}
+
+// GetRateLimit returns the rate limit per second of the given kind for a data collection.
+// If the data collection is empty, the global rate limit is returned.
+func (g *GLoader) GetRateLimit(dataCollection string, kind RateLimitKind) float64 {
+	return g.rateLimiters.get(dataCollection, kind).Rate()
+}
+
+// SetTracerProvider sets the OpenTelemetry tracer provider of the migration spans.
+// By default, the global tracer provider is used.
+func (g *GLoader) SetTracerProvider(tp trace.TracerProvider) *GLoader {
+	g.tracerProvider = tp
+	return g
+}
+
+// AddEventListener adds a listener of the migration events.
+func (g *GLoader) AddEventListener(listener EventListener) *GLoader {
+	g.eventListeners = append(g.eventListeners, listener)
+	return g
+}
+
+// SetLogger sets the logger of the migration and the database connections.
+// The logs of each data collection are prefixed with its name.
+func (g *GLoader) SetLogger(logger *log.Logger) *GLoader {
+	g.logger = logger
+	return g
+}
+
+// AddHooks adds hooks that are called at specific points of the migration, in the order they are added.
+func (g *GLoader) AddHooks(hooks ...Hooks) *GLoader {
+	g.hooks = append(g.hooks, hooks...)
+	return g
+}
+
+// SetHookErrorPolicy sets whether a table hook error aborts the migration, or skips only the data collection.
+// By default, the migration is aborted.
+func (g *GLoader) SetHookErrorPolicy(policy HookErrorPolicy) *GLoader {
+	g.hookErrorPolicy = policy
+	return g
+}
+
+// SetProgressInterval sets the interval of the table progress events.
+// It must be positive, otherwise Start fails with ErrInvalidProgressInterval.
+func (g *GLoader) SetProgressInterval(interval time.Duration) *GLoader {
+	g.progressInterval = interval
+	return g
+}
+
 func (g *GLoader) Stats() *stats.Stats {
 	return g.stats
 }
@@ -183,6 +496,44 @@ func (g *GLoader) Start() error {
 }
 
 func (g *GLoader) StartWithContext(ctx context.Context) error {
+	tp := g.tracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	ctx, span := tp.Tracer(tracerName).Start(
+		ctx,
+		"GLoader.Start",
+		trace.WithAttributes(
+			AttributeSrcDriver.String(g.srcConnector.GetDriver().GetDriverName()),
+			AttributeDestDriver.String(g.destConnector.GetDriver().GetDriverName()),
+		),
+	)
+	startedAt := time.Now()
+	summary := &RunSummary{}
+	err := g.start(ctx, summary)
+	summary.Elapsed = time.Since(startedAt).Seconds()
+	g.emit(Event{
+		Type:    EventRunFinished,
+		Rows:    summary.Rows,
+		Bytes:   summary.Bytes,
+		Elapsed: summary.Elapsed,
+		Summary: summary,
+		Err:     err,
+	})
+
+	driver.EndSpan(span, err)
+	return err
+}
+
+func (g *GLoader) start(ctx context.Context, summary *RunSummary) error {
+	if g.progressInterval <= 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidProgressInterval, g.progressInterval)
+	}
+
+	g.srcConnector.SetLogger(g.logger.WithPrefix(g.srcConnector.GetDriver().GetDriverName()))
+	g.destConnector.SetLogger(g.logger.WithPrefix(g.destConnector.GetDriver().GetDriverName()))
+
 	c, cCancelCauseFunc := context.WithCancelCause(ctx)
 	g.ctx = c
 	g.ctxCancelFunc = cCancelCauseFunc
@@ -209,102 +560,351 @@ func (g *GLoader) StartWithContext(ctx context.Context) error {
 		return err
 	}
 
-	var DCs []driver.DataCollectionDetail
-	DCs = sDetails.DataCollections
-	if len(g.includedDataCollections) > 0 {
-		DCs = sDetails.OnlyDataCollections(g.includedDataCollections...)
+	DCs, unmatched, err := SelectDataCollections(sDetails.DataCollections, g.includedDataCollections, g.excludedDataCollections)
+	if err != nil {
+		return err
 	}
-
-	if len(g.excludedDataCollections) > 0 {
-		DCs = sDetails.AllDataCollectionsExcept(g.excludedDataCollections...)
+	for _, pattern := range unmatched {
+		g.logger.Warn("pattern matched no data collection", "pattern", pattern)
+	}
+	names := make([]string, 0, len(DCs))
+	for _, dc := range DCs {
+		names = append(names, dc.Name)
 	}
+	g.logger.Info("resolved data collections", "count", len(DCs), "data_collections", strings.Join(names, ","))
 
-	wg := sync.WaitGroup{}
-	for i, dc := range DCs {
-		if dc.DataSetCount == 0 {
-			continue
-		}
+	trace.SpanFromContext(c).SetAttributes(AttributeDataCollections.Int(len(DCs)))
+	summary.DataCollections = len(DCs)
+	g.emit(Event{Type: EventRunStarted, DataCollections: len(DCs)})
 
-		dDC, err := dDetails.GetDataCollection(dc.Name)
-		if err != nil {
-			return fmt.Errorf("GLoader: failed to get destination data collection details for %s", dc.Name)
-		}
+	waves, err := g.planWaves(DCs, dDetails)
+	if err != nil {
+		return err
+	}
+	if err = g.checkLoadStrategies(DCs, dDetails, destConn); err != nil {
+		return err
+	}
 
-		var srcSchema strings.Builder
-		srcSchema.WriteString("Migration Source Schema:\n")
-		var destSchema strings.Builder
-		destSchema.WriteString("Migration Destination Schema:\n")
-		for k, v := range dc.GetDataMap().GetTypeMap() {
-			srcSchema.WriteString(fmt.Sprintf("%d \t %s: \t %s \t IS NULLABLE: %t\n", i, k, v.GetTypeName(), dc.GetDataMap().IsNullable(k)))
+	hc := HookContext{SrcConnection: srcConn, DestConnection: destConn, Stats: g.stats}
+	if err := g.runHooks(func(h Hooks) error { return h.BeforeRun(c, hc) }); err != nil {
+		g.runOnErrorHooks(c, hc, err)
+		return err
+	}
 
-			dDT := dDC.GetDataMap().Get(k)
-			if dDT == nil {
+	var (
+		errs   []error
+		errsMu sync.Mutex
+	)
+
+	quota := data.NewDiskQuota(g.spillQuota)
+	scheduler := NewScheduler(g.maxDataCollections, g.maxConnections)
+	for _, wave := range waves {
+		sortDataCollectionsBySize(wave)
+
+		wg := &sync.WaitGroup{}
+		for _, dc := range wave {
+			// the empty data collections are skipped, unless their destination is replaced.
+			if dc.DataSetCount == 0 && g.loadStrategyOf(dc.Name) == LoadAppend {
+				summary.Skipped++
 				continue
 			}
 
-			destSchema.WriteString(fmt.Sprintf("%d \t %s: \t %s \t IS NULLABLE: %t\n", i, k, dDT.GetTypeName(), dDC.GetDataMap().IsNullable(k)))
+			dDC, err := g.destDataCollectionFor(dDetails, dc)
+			if err != nil {
+				wg.Wait()
+				return fmt.Errorf("GLoader: failed to get destination data collection details for %s", dc.Name)
+			}
+
+			connections, err := scheduler.Acquire(c, g.connectionsOf(dc))
+			if err != nil {
+				// the context is canceled, so the running data collections are stopping too.
+				wg.Wait()
+				return errors.Join(errs...)
+			}
+
+			wg.Add(1)
+			go func(dc, dDC driver.DataCollectionDetail) {
+				defer wg.Done()
+				defer scheduler.Release(connections)
+
+				startedAt := time.Now()
+				g.emit(Event{Type: EventTableStarted, DataCollection: dc.Name, TotalRows: uint64(dc.DataSetCount)})
+
+				done := make(chan struct{})
+				if len(g.eventListeners) > 0 {
+					go g.reportProgress(dc.Name, uint64(dc.DataSetCount), startedAt, done)
+				}
+				err := g.loadDataCollection(dc, dDC, quota)
+				close(done)
+
+				rows, bytes := g.tableProgress(dc.Name)
+				errsMu.Lock()
+				summary.Rows += rows
+				summary.Bytes += bytes
+				if err != nil {
+					err = fmt.Errorf("GLoader: failed to load %s: %w", dc.Name, err)
+					errs = append(errs, err)
+					summary.Failed++
+				} else {
+					summary.Completed++
+				}
+				errsMu.Unlock()
+
+				e := Event{
+					Type:           EventTableCompleted,
+					DataCollection: dc.Name,
+					Rows:           rows,
+					Bytes:          bytes,
+					TotalRows:      uint64(dc.DataSetCount),
+					Elapsed:        time.Since(startedAt).Seconds(),
+				}
+				if err != nil {
+					e.Type = EventTableFailed
+					e.Err = err
+				}
+				g.emit(e)
+			}(dc, dDC)
 		}
+		wg.Wait()
+	}
+	return errors.Join(errs...)
+}
 
-		fmt.Println(srcSchema.String())
-		fmt.Println(destSchema.String())
+// connectionsOf returns the number of source and destination connections that are held
+// while loading the given data collection.
+func (g *GLoader) connectionsOf(dc driver.DataCollectionDetail) uint {
+	connections := g.readerWorkersOf(dc.Name) + g.writerWorkersOf(dc.Name)
+	if g.preparesDestDataCollection(dc.Name) {
+		// the destination data collection is prepared and finished with another connection.
+		connections++
+	}
+	return connections
+}
 
-		wg.Add(2)
+// planWaves groups the data collections into waves according to the foreign key mode.
+// The data collections of each wave are loaded at the same time, and waves are loaded one after another.
+func (g *GLoader) planWaves(dcs []driver.DataCollectionDetail, dDetails driver.DatabaseDetail) ([][]driver.DataCollectionDetail, error) {
+	switch g.foreignKeyMode {
+	case ForeignKeyOrder:
+		return newDependencyGraph(dcs, g.destReferences(dcs, dDetails)).Waves()
+	case ForeignKeyIgnore:
+		return [][]driver.DataCollectionDetail{dcs}, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownForeignKeyMode, g.foreignKeyMode)
+	}
+}
 
-		buffer := data.NewBuffer(c).
-			WithObserver(NewBufferObserverAdapter(g.stats, dc.Name))
+// destReferences returns the data collections referenced by each data collection through the foreign keys
+// of its destination data collection, since those decide the insert order.
+// The referenced destination data collections are translated back to the data collections loaded into them,
+// and the destination data collections that don't exist yet reference nothing.
+func (g *GLoader) destReferences(dcs []driver.DataCollectionDetail, dDetails driver.DatabaseDetail) map[string][]string {
+	sources := make(map[string]string, len(dcs))
+	for _, dc := range dcs {
+		sources[g.destDataCollectionOf(dc.Name)] = dc.Name
+	}
 
-		rConnectionPool := driver.NewConnectionPool(g.srcConnector)
-		wConnectionPool := driver.NewConnectionPool(g.destConnector)
+	references := make(map[string][]string, len(dcs))
+	for _, dc := range dcs {
+		dDC, err := dDetails.GetDataCollection(g.destDataCollectionOf(dc.Name))
+		if err != nil {
+			continue
+		}
+		for _, parent := range dDC.GetReferencedDataCollections() {
+			if source, ok := sources[parent]; ok {
+				references[dc.Name] = append(references[dc.Name], source)
+			}
+		}
+	}
+	return references
+}
 
-		reader := NewReader(c, dc.Name, buffer, dc.DataMap, rConnectionPool)
+// loadDataCollection loads the given data collection, and runs its hooks.
+func (g *GLoader) loadDataCollection(dc, dDC driver.DataCollectionDetail, quota *data.DiskQuota) error {
+	ctx, span := startSpan(
+		g.ctx,
+		"GLoader.loadDataCollection",
+		driver.AttributeDataCollection.String(dc.Name),
+		AttributeDataSetCount.Int(dc.DataSetCount),
+		AttributeReaderWorkers.Int(int(g.readerWorkersOf(dc.Name))),
+		AttributeWriterWorkers.Int(int(g.writerWorkersOf(dc.Name))),
+	)
+	defer span.End()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	failure := newTableFailure(cancel)
+
+	hc, closeHookConns, err := g.newHookContext(ctx, dc, dDC)
+	if err != nil {
+		return err
+	}
+	defer closeHookConns()
 
-		if offset, ok := g.dataCollectionStartOffset[dc.Name]; ok {
-			reader.SetStartOffset(offset)
+	// wDC is the data collection which is written, which is dDC unless it's loaded into a staging one.
+	wDC := dDC
+	var destConn driver.Connection
+	if g.preparesDestDataCollection(dc.Name) {
+		destConn, err = g.destConnector.Connect(ctx)
+		if err != nil {
+			return err
 		}
+		defer func() {
+			if err := destConn.Close(); err != nil {
+				g.logger.WithPrefix(dc.Name).Warn("failed to close the destination connection", log.Err(err))
+			}
+		}()
+		wDC, err = g.prepareDestDataCollection(ctx, destConn, dc, dDC)
+		if err == nil && wDC.Name != dDC.Name {
+			hc.StagingDataCollection = wDC.Name
+		}
+	}
 
-		if offset, ok := g.dataCollectionEndOffset[dc.Name]; ok {
-			reader.SetEndOffset(offset)
-		} else {
-			reader.SetEndOffset(uint64(dc.DataSetCount))
+	if err == nil {
+		err = g.runHooks(func(h Hooks) error { return h.BeforeTable(ctx, hc) })
+	}
+	if err == nil && dc.DataSetCount > 0 {
+		err = g.transfer(ctx, dc, wDC, quota, hc, failure)
+	}
+	if err == nil {
+		err = g.runHooks(func(h Hooks) error { return h.AfterTable(ctx, hc) })
+	}
+	if err == nil && destConn != nil {
+		// a stopped data collection isn't completely loaded, so it mustn't replace the destination.
+		if err = context.Cause(ctx); err == nil {
+			err = g.finishDestDataCollection(ctx, destConn, dc, dDC)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, ErrHookFailed) {
+			g.failTable(failure, err)
 		}
-		fmt.Println("Starting to load", dc.Name, "from", reader.startOffset, "to", reader.endOffset)
+		if destConn != nil {
+			g.abortDestDataCollection(ctx, destConn, dc, dDC)
+		}
+		g.runOnErrorHooks(ctx, hc, err)
+	}
+	return err
+}
 
-		reader.SetRowsPerBatch(g.rowsPerBatch)
-		reader.SetWorkers(g.workers)
+// transfer reads the given data collection from the source and writes it to the destination.
+// It blocks until the reader and the writer of the data collection are finished.
+func (g *GLoader) transfer(ctx context.Context, dc, dDC driver.DataCollectionDetail, quota *data.DiskQuota, hc HookContext, failure *tableFailure) error {
+	logger := g.logger.WithPrefix(dc.Name)
+	for k, v := range dc.GetDataMap().GetTypeMap() {
+		args := []any{
+			"column", k,
+			"src_type", v.GetTypeName(),
+			"src_nullable", dc.GetDataMap().IsNullable(k),
+		}
+		dk := k
+		if mapped, ok := g.columnMappings[dc.Name][k]; ok {
+			dk = mapped
+			args = append(args, "dest_column", dk)
+		}
+		if dDT := dDC.GetDataMap().Get(dk); dDT != nil {
+			args = append(args, "dest_type", dDT.GetTypeName(), "dest_nullable", dDC.GetDataMap().IsNullable(dk))
+		}
+		logger.Debug("column mapping", args...)
+	}
 
-		writer := NewWriter(c, dc.Name, buffer, wConnectionPool)
-		writer.SetRowsPerBatch(g.rowsPerBatch)
-		writer.SetWorkers(g.workers)
+	buffer := data.NewBuffer(ctx).
+		WithObserver(NewBufferObserverAdapter(g.stats, dc.Name))
 
-		go func(reader *Reader, rcPool *driver.ConnectionPool) {
-			err := reader.Start()
-			if err != nil {
-				panic(err)
-			}
-			wg.Done()
-			err = rcPool.CloseAll()
+	if g.spillEnabled {
+		spill, err := data.NewSpill(g.spillDir, quota)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err := spill.Close()
 			if err != nil {
-				// TODO: logging system
-				//log.Println(err)
+				logger.Warn("failed to remove the spill files", log.Err(err))
 			}
-		}(reader, rConnectionPool)
+		}()
+		buffer.WithSpill(spill)
+	}
 
-		go func(writer *Writer, wcPool *driver.ConnectionPool) {
-			err := writer.Start()
-			if err != nil {
-				panic(err)
-			}
-			wg.Done()
-			err = wcPool.CloseAll()
-			if err != nil {
-				// TODO: logging system
-				//log.Println(err)
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	rConnectionPool := driver.NewConnectionPool(g.srcConnector)
+	wConnectionPool := driver.NewConnectionPool(g.destConnector)
+
+	reader := NewReader(ctx, dc.Name, buffer, dc.DataMap, rConnectionPool)
+
+	startOffset, endOffset := g.readRangeOf(dc)
+	reader.SetStartOffset(startOffset)
+	reader.SetEndOffset(endOffset)
+	logger.Info("loading data collection",
+		"dest", dDC.Name,
+		"start_offset", reader.startOffset,
+		"end_offset", reader.endOffset,
+		"rows_per_batch", g.rowsPerBatchOf(dc.Name),
+		"reader_workers", g.readerWorkersOf(dc.Name),
+		"writer_workers", g.writerWorkersOf(dc.Name),
+	)
+
+	rowsPerBatch := g.rowsPerBatchOf(dc.Name)
+	reader.SetRowsPerBatch(rowsPerBatch)
+	reader.SetWorkers(g.readerWorkersOf(dc.Name))
+	if mapping, ok := g.columnMappings[dc.Name]; ok {
+		reader.SetColumnMapping(mapping)
+	}
+	reader.SetRowsRateLimiters(g.rateLimiters.of(dc.Name, RateLimitReadRows)...)
+	reader.SetBytesRateLimiters(g.rateLimiters.of(dc.Name, RateLimitReadBytes)...)
+	reader.SetObserver(NewReaderObserverAdapter(g.stats, dc.Name))
+	reader.SetLogger(logger)
+
+	writer := NewWriter(ctx, dDC.Name, buffer, wConnectionPool)
+	writer.SetRowsPerBatch(rowsPerBatch)
+	writer.SetWorkers(g.writerWorkersOf(dc.Name))
+	writer.SetRowsRateLimiters(g.rateLimiters.of(dc.Name, RateLimitWriteRows)...)
+	writer.SetObserver(NewWriterObserverAdapter(g.stats, dc.Name))
+	writer.SetLogger(logger)
+	if len(g.hooks) > 0 {
+		writer.SetAfterBatchWritten(func(batch *data.Batch) {
+			if err := g.runHooks(func(h Hooks) error { return h.AfterBatchWritten(ctx, hc, batch) }); err != nil {
+				g.failTable(failure, err)
 			}
-		}(writer, wConnectionPool)
+		})
+	}
 
+	if g.adaptiveBatchSize {
+		reader.SetAdaptiveBatchSize(
+			NewAdaptiveBatchSize(rowsPerBatch, g.batchTargetBytes, g.batchTargetLatency).
+				WithObserver(NewBatchSizeObserverAdapter(g.stats, MetricReaderBatchSizeRows, dc.Name)),
+		)
+		writer.SetAdaptiveBatchSize(
+			NewAdaptiveBatchSize(rowsPerBatch, g.batchTargetBytes, g.batchTargetLatency).
+				WithObserver(NewBatchSizeObserverAdapter(g.stats, MetricWriterBatchSizeRows, dc.Name)),
+		)
 	}
+
+	go func(reader *Reader, rcPool *driver.ConnectionPool) {
+		if err := reader.Start(); err != nil {
+			// a failed read stops only its data collection, which is reported as failed.
+			failure.fail(err)
+		}
+		wg.Done()
+		if err := rcPool.CloseAll(); err != nil {
+			logger.Warn("failed to close the source connections", log.Err(err))
+		}
+	}(reader, rConnectionPool)
+
+	go func(writer *Writer, wcPool *driver.ConnectionPool) {
+		if err := writer.Start(); err != nil {
+			// a failed write stops only its data collection, which is reported as failed.
+			failure.fail(err)
+		}
+		wg.Done()
+		if err := wcPool.CloseAll(); err != nil {
+			logger.Warn("failed to close the destination connections", log.Err(err))
+		}
+	}(writer, wConnectionPool)
+
 	wg.Wait()
-	return nil
+	return failure.err
 }
 
 func (g *GLoader) Stop() {
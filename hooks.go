@@ -0,0 +1,182 @@
+package gloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/log"
+	"github.com/mohammadv184/gloader/pkg/stats"
+)
+
+var (
+	ErrUnknownHookErrorPolicy = errors.New("unknown hook error policy")
+	ErrHookFailed             = errors.New("hook failed")
+)
+
+// HookErrorPolicy is what happens when a table hook returns an error.
+type HookErrorPolicy uint8
+
+const (
+	// HookErrorAbort stops the whole migration.
+	HookErrorAbort HookErrorPolicy = iota
+	// HookErrorSkip stops only the data collection of the hook, the other data collections are still loaded.
+	HookErrorSkip
+)
+
+var hookErrorPolicyNames = map[HookErrorPolicy]string{
+	HookErrorAbort: "abort",
+	HookErrorSkip:  "skip",
+}
+
+// String returns the name of the hook error policy.
+func (p HookErrorPolicy) String() string {
+	return hookErrorPolicyNames[p]
+}
+
+// GetHookErrorPolicyFromString returns the hook error policy from its name.
+func GetHookErrorPolicyFromString(policy string) (HookErrorPolicy, error) {
+	for p, v := range hookErrorPolicyNames {
+		if strings.EqualFold(v, policy) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownHookErrorPolicy, policy)
+}
+
+// HookContext is what the hooks receive about the migration.
+type HookContext struct {
+	// DataCollection and DestDataCollection are the source and destination details of the data collection.
+	// They are empty in BeforeRun.
+	DataCollection     driver.DataCollectionDetail
+	DestDataCollection driver.DataCollectionDetail
+	// StagingDataCollection is the name of the staging data collection which is written instead of
+	// DestDataCollection, and swapped with it after AfterTable. It's empty if the data collection isn't staged.
+	StagingDataCollection string
+	// SrcConnection and DestConnection are connections dedicated to the hooks of the data collection,
+	// they are closed after AfterTable or OnError is called.
+	SrcConnection  driver.Connection
+	DestConnection driver.Connection
+	Stats          *stats.Stats
+}
+
+// Hooks is an interface that can be implemented to run code at specific points of the migration.
+// The hooks of different data collections are called concurrently. NopHooks can be embedded
+// to implement only some of the hooks.
+type Hooks interface {
+	// BeforeRun is called after the data collections to load are resolved. An error aborts the migration.
+	BeforeRun(ctx context.Context, hc HookContext) error
+	// BeforeTable is called before a data collection starts loading.
+	BeforeTable(ctx context.Context, hc HookContext) error
+	// AfterBatchWritten is called after a batch is written to the destination.
+	// It's called concurrently by the writer workers of the data collection.
+	AfterBatchWritten(ctx context.Context, hc HookContext, batch *data.Batch) error
+	// AfterTable is called after a data collection is loaded.
+	AfterTable(ctx context.Context, hc HookContext) error
+	// OnError is called when the migration or a data collection fails, including when a hook fails.
+	OnError(ctx context.Context, hc HookContext, err error)
+}
+
+// NopHooks is a Hooks implementation that does nothing.
+type NopHooks struct{}
+
+var _ Hooks = NopHooks{}
+
+func (NopHooks) BeforeRun(context.Context, HookContext) error { return nil }
+
+func (NopHooks) BeforeTable(context.Context, HookContext) error { return nil }
+
+func (NopHooks) AfterBatchWritten(context.Context, HookContext, *data.Batch) error { return nil }
+
+func (NopHooks) AfterTable(context.Context, HookContext) error { return nil }
+
+func (NopHooks) OnError(context.Context, HookContext, error) {}
+
+// runHooks calls the given hook of each Hooks in order, and stops at the first error.
+func (g *GLoader) runHooks(hook func(h Hooks) error) error {
+	for _, h := range g.hooks {
+		if err := hook(h); err != nil {
+			return fmt.Errorf("%w: %s", ErrHookFailed, err)
+		}
+	}
+	return nil
+}
+
+// runOnErrorHooks calls the OnError hook of each Hooks.
+func (g *GLoader) runOnErrorHooks(ctx context.Context, hc HookContext, err error) {
+	for _, h := range g.hooks {
+		h.OnError(ctx, hc, err)
+	}
+}
+
+// newHookContext returns the hook context of the given data collection, with dedicated connections.
+// The returned function closes the connections.
+func (g *GLoader) newHookContext(ctx context.Context, dc, dDC driver.DataCollectionDetail) (HookContext, func(), error) {
+	hc := HookContext{
+		DataCollection:     dc,
+		DestDataCollection: dDC,
+		Stats:              g.stats,
+	}
+	if len(g.hooks) == 0 {
+		return hc, func() {}, nil
+	}
+
+	srcConn, err := g.srcConnector.Connect(ctx)
+	if err != nil {
+		return hc, nil, err
+	}
+
+	destConn, err := g.destConnector.Connect(ctx)
+	if err != nil {
+		_ = srcConn.Close()
+		return hc, nil, err
+	}
+
+	hc.SrcConnection = srcConn
+	hc.DestConnection = destConn
+	return hc, func() {
+		if err := srcConn.Close(); err != nil {
+			g.logger.WithPrefix(dc.Name).Warn("failed to close the hooks source connection", log.Err(err))
+		}
+		if err := destConn.Close(); err != nil {
+			g.logger.WithPrefix(dc.Name).Warn("failed to close the hooks destination connection", log.Err(err))
+		}
+	}, nil
+}
+
+// tableFailure records the first failure of a data collection while it's loading,
+// and stops the data collection or the whole migration according to the hook error policy.
+type tableFailure struct {
+	err    error
+	once   *sync.Once
+	cancel context.CancelCauseFunc
+}
+
+func newTableFailure(cancel context.CancelCauseFunc) *tableFailure {
+	return &tableFailure{
+		once:   &sync.Once{},
+		cancel: cancel,
+	}
+}
+
+// fail records the failure and stops the data collection, if it's the first failure.
+// It returns true if it's the first failure.
+func (f *tableFailure) fail(err error) bool {
+	var first bool
+	f.once.Do(func() {
+		f.err = err
+		f.cancel(err)
+		first = true
+	})
+	return first
+}
+
+func (g *GLoader) failTable(f *tableFailure, err error) {
+	if f.fail(err) && g.hookErrorPolicy == HookErrorAbort {
+		g.ctxCancelFunc(err)
+	}
+}
@@ -0,0 +1,191 @@
+package gloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mohammadv184/gloader/data"
+)
+
+// recordingHooks records the calls of the hooks, and fails the hooks of failTable.
+type recordingHooks struct {
+	NopHooks
+	failHook  string
+	failTable string
+
+	mu    sync.Mutex
+	calls []string
+	rows  map[string]uint64
+	errs  map[string]error
+}
+
+func (h *recordingHooks) record(hook string, hc HookContext) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hc.DataCollection.Name != "" && (hc.SrcConnection == nil || hc.DestConnection == nil || hc.Stats == nil) {
+		return fmt.Errorf("%s of %s without connections or stats", hook, hc.DataCollection.Name)
+	}
+	h.calls = append(h.calls, hook+" "+hc.DataCollection.Name)
+	if hook == h.failHook && hc.DataCollection.Name == h.failTable {
+		return errors.New(hook + " failed")
+	}
+	return nil
+}
+
+func (h *recordingHooks) BeforeRun(_ context.Context, hc HookContext) error {
+	return h.record("BeforeRun", hc)
+}
+
+func (h *recordingHooks) BeforeTable(_ context.Context, hc HookContext) error {
+	return h.record("BeforeTable", hc)
+}
+
+func (h *recordingHooks) AfterBatchWritten(_ context.Context, hc HookContext, batch *data.Batch) error {
+	h.mu.Lock()
+	if h.rows == nil {
+		h.rows = make(map[string]uint64)
+	}
+	h.rows[hc.DataCollection.Name] += batch.GetLength()
+	h.mu.Unlock()
+	if h.failHook == "AfterBatchWritten" && hc.DataCollection.Name == h.failTable {
+		return errors.New("AfterBatchWritten failed")
+	}
+	return nil
+}
+
+func (h *recordingHooks) AfterTable(_ context.Context, hc HookContext) error {
+	return h.record("AfterTable", hc)
+}
+
+func (h *recordingHooks) OnError(_ context.Context, hc HookContext, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.errs == nil {
+		h.errs = make(map[string]error)
+	}
+	h.errs[hc.DataCollection.Name] = err
+}
+
+// callsOf returns the recorded calls of the data collection in order.
+func (h *recordingHooks) callsOf(dataCollection string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var calls []string
+	for _, c := range h.calls {
+		if c == "BeforeRun " || strings.HasSuffix(c, " "+dataCollection) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+func newHooksTestDatabases(t *testing.T) (*memoryDatabase, *memoryDatabase) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "orders", 30)
+	src.addDataCollection(t, "users", 20)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "orders", 0)
+	dest.addDataCollection(t, "users", 0)
+	return src, dest
+}
+
+func TestHooksOrder(t *testing.T) {
+	src, dest := newHooksTestDatabases(t)
+	first, second := &recordingHooks{}, &recordingHooks{}
+	g := newTestGLoader(t, src, dest).AddHooks(first, second).SetRowsPerBatch(10)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, h := range []*recordingHooks{first, second} {
+		for _, dc := range []string{"orders", "users"} {
+			want := []string{"BeforeRun ", "BeforeTable " + dc, "AfterTable " + dc}
+			if got := h.callsOf(dc); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("calls of %s = %q, want %q", dc, got, want)
+			}
+		}
+		if h.rows["orders"] != 30 || h.rows["users"] != 20 {
+			t.Errorf("AfterBatchWritten rows = %v, want orders:30 users:20", h.rows)
+		}
+		if len(h.errs) != 0 {
+			t.Errorf("OnError called with %v", h.errs)
+		}
+	}
+}
+
+func TestHookErrorSkipsTheTable(t *testing.T) {
+	for _, hook := range []string{"BeforeTable", "AfterBatchWritten", "AfterTable"} {
+		t.Run(hook, func(t *testing.T) {
+			src, dest := newHooksTestDatabases(t)
+			h := &recordingHooks{failHook: hook, failTable: "orders"}
+			g := newTestGLoader(t, src, dest).AddHooks(h).SetHookErrorPolicy(HookErrorSkip)
+			err := g.Start()
+			if !errors.Is(err, ErrHookFailed) {
+				t.Fatalf("Start() error = %v, want %v", err, ErrHookFailed)
+			}
+
+			if !errors.Is(h.errs["orders"], ErrHookFailed) || len(h.errs) != 1 {
+				t.Errorf("OnError calls = %v, want one of orders with %v", h.errs, ErrHookFailed)
+			}
+			if calls := h.callsOf("users"); len(calls) != 3 {
+				t.Errorf("calls of users = %q, want all the hooks", calls)
+			}
+			wantIDs(t, dest.ids("users"), 20)
+		})
+	}
+}
+
+func TestHookErrorAbortsTheRun(t *testing.T) {
+	src, dest := newHooksTestDatabases(t)
+	h := &recordingHooks{failHook: "BeforeTable", failTable: "orders"}
+	// one data collection at a time, the larger first, so users isn't started yet.
+	g := newTestGLoader(t, src, dest).AddHooks(h).SetMaxConcurrentDataCollections(1)
+	err := g.Start()
+	if !errors.Is(err, ErrHookFailed) {
+		t.Fatalf("Start() error = %v, want %v", err, ErrHookFailed)
+	}
+	if calls := h.callsOf("users"); len(calls) != 1 {
+		t.Errorf("calls of users = %q, want only BeforeRun", calls)
+	}
+	if ids := dest.ids("users"); len(ids) != 0 {
+		t.Errorf("users has %d data sets, want none", len(ids))
+	}
+}
+
+func TestBeforeRunErrorAbortsTheRun(t *testing.T) {
+	src, dest := newHooksTestDatabases(t)
+	h := &recordingHooks{failHook: "BeforeRun"}
+	err := newTestGLoader(t, src, dest).AddHooks(h).Start()
+	if !errors.Is(err, ErrHookFailed) {
+		t.Fatalf("Start() error = %v, want %v", err, ErrHookFailed)
+	}
+	if _, ok := h.errs[""]; !ok {
+		t.Errorf("OnError calls = %v, want one of the run", h.errs)
+	}
+	var tables []string
+	for _, c := range h.calls {
+		if c != "BeforeRun " {
+			tables = append(tables, c)
+		}
+	}
+	sort.Strings(tables)
+	if len(tables) != 0 {
+		t.Errorf("table hooks called after BeforeRun failed: %q", tables)
+	}
+}
+
+func TestGetHookErrorPolicyFromString(t *testing.T) {
+	for policy, name := range hookErrorPolicyNames {
+		if got, err := GetHookErrorPolicyFromString(name); err != nil || got != policy {
+			t.Errorf("GetHookErrorPolicyFromString(%q) = %v, %v, want %v", name, got, err, policy)
+		}
+	}
+	if _, err := GetHookErrorPolicyFromString("retry"); !errors.Is(err, ErrUnknownHookErrorPolicy) {
+		t.Errorf("GetHookErrorPolicyFromString(retry) error = %v, want %v", err, ErrUnknownHookErrorPolicy)
+	}
+}
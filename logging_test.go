@@ -0,0 +1,84 @@
+package gloader
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/pkg/log"
+)
+
+// logBuffer is a bytes.Buffer that is safe for concurrent use.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestGLoaderLogsWithTablePrefixes(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 20)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 0)
+
+	out := &logBuffer{}
+	g := newTestGLoader(t, src, dest).SetLogger(log.NewLogger(log.NewHandler(out, out)))
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	logs := out.String()
+	for _, want := range []string{
+		"INFO resolved data collections count=1 data_collections=users",
+		"INFO [users] loading data collection dest=users",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("logs don't contain %q:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, "WARN") || strings.Contains(logs, "ERROR") {
+		t.Errorf("a successful run logged warnings or errors:\n%s", logs)
+	}
+}
+
+func TestStoppedRunDoesNotWarnAboutTheClosedBuffer(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 1000)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 0)
+
+	src.readErr = func(string, uint64) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dest.writeErr = func(string, *data.Batch) error {
+		// the run is stopped while the reader is still reading.
+		cancel()
+		return nil
+	}
+
+	out := &logBuffer{}
+	g := newTestGLoader(t, src, dest).SetLogger(log.NewLogger(log.NewHandler(out, out))).SetRowsPerBatch(1)
+	_ = g.StartWithContext(ctx)
+
+	if logs := out.String(); strings.Contains(logs, "failed to close the buffer") {
+		t.Errorf("a stopped run warned about the closed buffer:\n%s", logs)
+	}
+}
@@ -0,0 +1,400 @@
+package gloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/driver/cockroach"
+	"github.com/mohammadv184/gloader/pkg/log"
+)
+
+// memoryDriver is a driver of in-memory databases for the tests.
+// The DSN of a connection is the name of its database, which is registered by newMemoryDatabase.
+type memoryDriver struct{}
+
+var _ driver.Driver = memoryDriver{}
+
+// memoryDatabases are the registered in-memory databases by their names.
+var memoryDatabases sync.Map
+
+func init() {
+	if err := driver.Register(memoryDriver{}); err != nil {
+		panic(err)
+	}
+}
+
+func (memoryDriver) GetDriverName() string { return "memory" }
+
+func (memoryDriver) IsWritable() bool { return true }
+
+func (memoryDriver) IsReadable() bool { return true }
+
+func (memoryDriver) Open(_ context.Context, dsn string) (driver.Connection, error) {
+	db, ok := memoryDatabases.Load(dsn)
+	if !ok {
+		return nil, fmt.Errorf("memory database %s not found", dsn)
+	}
+	if openErr := db.(*memoryDatabase).openErr; openErr != nil {
+		if err := openErr(); err != nil {
+			return nil, err
+		}
+	}
+	return &memoryConnection{db: db.(*memoryDatabase)}, nil
+}
+
+// memoryDatabase is an in-memory database, its data collections have a single "id" column.
+type memoryDatabase struct {
+	name            string
+	mu              sync.Mutex
+	dataCollections map[string]*memoryDataCollection
+	// batches are the lengths of the batches written to each data collection.
+	batches map[string][]uint64
+	// writeErr, if it's set, is called before each write and its error fails the write.
+	writeErr func(dataCollection string, batch *data.Batch) error
+	// readErr, if it's set, is called before each read and its error fails the read.
+	readErr func(dataCollection string, startOffset uint64) error
+	// openErr, if it's set, is called before each connection is opened and its error fails it.
+	openErr func() error
+	// ddlErr, if it's set, is called before each DDL statement, e.g. "drop", and its error fails it.
+	ddlErr func(statement, dataCollection string) error
+}
+
+type memoryDataCollection struct {
+	dataMap     *data.Map
+	foreignKeys []driver.ForeignKey
+	sets        []*data.Set
+}
+
+// newMemoryDatabase registers an empty in-memory database, which is removed when the test is finished.
+func newMemoryDatabase(t *testing.T, name string) *memoryDatabase {
+	t.Helper()
+	db := &memoryDatabase{
+		name:            t.Name() + "/" + name,
+		dataCollections: make(map[string]*memoryDataCollection),
+		batches:         make(map[string][]uint64),
+	}
+	memoryDatabases.Store(db.name, db)
+	t.Cleanup(func() { memoryDatabases.Delete(db.name) })
+	return db
+}
+
+// addDataCollection adds a data collection with the ids from 0 to length-1.
+func (db *memoryDatabase) addDataCollection(t *testing.T, name string, length int, foreignKeys ...driver.ForeignKey) {
+	t.Helper()
+	dm := &data.Map{}
+	dm.Set("id", &cockroach.IntType{})
+	dc := &memoryDataCollection{dataMap: dm, foreignKeys: foreignKeys}
+	for i := 0; i < length; i++ {
+		dc.sets = append(dc.sets, newIDSet(t, int64(i)))
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.dataCollections[name] = dc
+}
+
+// ids returns the ids of the data collection in order, or nil if it doesn't exist.
+func (db *memoryDatabase) ids(name string) []int64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	dc, ok := db.dataCollections[name]
+	if !ok {
+		return nil
+	}
+	ids := make([]int64, 0, len(dc.sets))
+	for _, set := range dc.sets {
+		ids = append(ids, set.Get("id").GetValueType().GetValue().(int64))
+	}
+	return ids
+}
+
+// names returns the names of the data collections in order.
+func (db *memoryDatabase) names() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	names := make([]string, 0, len(db.dataCollections))
+	for name := range db.dataCollections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writtenBatches returns the lengths of the batches written to the data collection.
+func (db *memoryDatabase) writtenBatches(name string) []uint64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]uint64(nil), db.batches[name]...)
+}
+
+type memoryConnection struct {
+	db     *memoryDatabase
+	closed bool
+}
+
+var (
+	_ driver.ReadableConnection    = &memoryConnection{}
+	_ driver.WritableConnection    = &memoryConnection{}
+	_ driver.TruncatableConnection = &memoryConnection{}
+	_ driver.DroppableConnection   = &memoryConnection{}
+	_ driver.CreatableConnection   = &memoryConnection{}
+	_ driver.RenamableConnection   = &memoryConnection{}
+	_ driver.CopyableConnection    = &memoryConnection{}
+	_ driver.CountableConnection   = &memoryConnection{}
+)
+
+func (c *memoryConnection) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *memoryConnection) IsClosed() bool {
+	return c.closed
+}
+
+func (c *memoryConnection) Ping() error {
+	if c.closed {
+		return driver.ErrConnectionIsClosed
+	}
+	return nil
+}
+
+func (c *memoryConnection) GetDetails(context.Context) (driver.DatabaseDetail, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	details := driver.DatabaseDetail{Name: c.db.name}
+	for name, dc := range c.db.dataCollections {
+		details.DataCollections = append(details.DataCollections, driver.DataCollectionDetail{
+			Name:         name,
+			DataMap:      dc.dataMap,
+			DataSetCount: len(dc.sets),
+			ForeignKeys:  dc.foreignKeys,
+		})
+	}
+	sort.Slice(details.DataCollections, func(i, j int) bool {
+		return details.DataCollections[i].Name < details.DataCollections[j].Name
+	})
+	return details, nil
+}
+
+func (c *memoryConnection) Read(_ context.Context, dataCollection string, startOffset, endOffset uint64) (*data.Batch, error) {
+	if c.db.readErr != nil {
+		if err := c.db.readErr(dataCollection, startOffset); err != nil {
+			return nil, err
+		}
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	dc, ok := c.db.dataCollections[dataCollection]
+	if !ok {
+		return nil, fmt.Errorf("data collection %s not found", dataCollection)
+	}
+	batch := data.NewDataBatch()
+	for i := startOffset; i < endOffset && i < uint64(len(dc.sets)); i++ {
+		// the data sets are shared, the tests don't change them.
+		batch.Add(dc.sets[i])
+	}
+	return batch, nil
+}
+
+func (c *memoryConnection) Write(_ context.Context, dataCollection string, batch *data.Batch) error {
+	if c.db.writeErr != nil {
+		if err := c.db.writeErr(dataCollection, batch); err != nil {
+			return err
+		}
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	dc, ok := c.db.dataCollections[dataCollection]
+	if !ok {
+		return fmt.Errorf("data collection %s not found", dataCollection)
+	}
+	dc.sets = append(dc.sets, *batch...)
+	c.db.batches[dataCollection] = append(c.db.batches[dataCollection], batch.GetLength())
+	return nil
+}
+
+// ddl runs the DDL statement of the data collection with the database locked.
+func (c *memoryConnection) ddl(statement, dataCollection string, f func() error) error {
+	if c.db.ddlErr != nil {
+		if err := c.db.ddlErr(statement, dataCollection); err != nil {
+			return err
+		}
+	}
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	return f()
+}
+
+// referencing returns the name of a data collection that references the given one, or "" if there's none.
+func (db *memoryDatabase) referencing(name string) string {
+	for other, dc := range db.dataCollections {
+		if other == name {
+			continue
+		}
+		for _, fk := range dc.foreignKeys {
+			if fk.ReferencedDataCollection == name {
+				return other
+			}
+		}
+	}
+	return ""
+}
+
+func (c *memoryConnection) TruncateDataCollection(_ context.Context, dataCollection string) error {
+	return c.ddl("truncate", dataCollection, func() error {
+		dc, ok := c.db.dataCollections[dataCollection]
+		if !ok {
+			return fmt.Errorf("data collection %s not found", dataCollection)
+		}
+		// like TRUNCATE without CASCADE.
+		if other := c.db.referencing(dataCollection); other != "" {
+			return fmt.Errorf("data collection %s is referenced by %s", dataCollection, other)
+		}
+		dc.sets = nil
+		return nil
+	})
+}
+
+func (c *memoryConnection) DropDataCollection(_ context.Context, dataCollection string) error {
+	return c.ddl("drop", dataCollection, func() error {
+		// like DROP TABLE IF EXISTS without CASCADE.
+		if other := c.db.referencing(dataCollection); other != "" {
+			return fmt.Errorf("data collection %s is referenced by %s", dataCollection, other)
+		}
+		delete(c.db.dataCollections, dataCollection)
+		return nil
+	})
+}
+
+func (c *memoryConnection) CreateDataCollection(_ context.Context, dataCollection string, detail driver.DataCollectionDetail) error {
+	return c.ddl("create", dataCollection, func() error {
+		if _, ok := c.db.dataCollections[dataCollection]; ok {
+			return fmt.Errorf("data collection %s already exists", dataCollection)
+		}
+		c.db.dataCollections[dataCollection] = &memoryDataCollection{dataMap: detail.DataMap}
+		return nil
+	})
+}
+
+func (c *memoryConnection) CreateDataCollectionLike(_ context.Context, dataCollection, like string) error {
+	return c.ddl("create", dataCollection, func() error {
+		if _, ok := c.db.dataCollections[dataCollection]; ok {
+			return fmt.Errorf("data collection %s already exists", dataCollection)
+		}
+		dc, ok := c.db.dataCollections[like]
+		if !ok {
+			return fmt.Errorf("data collection %s not found", like)
+		}
+		c.db.dataCollections[dataCollection] = &memoryDataCollection{dataMap: dc.dataMap}
+		return nil
+	})
+}
+
+func (c *memoryConnection) RenameDataCollections(_ context.Context, renames ...driver.Rename) error {
+	return c.ddl("rename", "", func() error {
+		// the renames are applied to a copy, so either all or none of them are applied.
+		dcs := make(map[string]*memoryDataCollection, len(c.db.dataCollections))
+		for name, dc := range c.db.dataCollections {
+			dcs[name] = dc
+		}
+		for _, r := range renames {
+			dc, ok := dcs[r.From]
+			if !ok {
+				if r.IfExists {
+					continue
+				}
+				return fmt.Errorf("data collection %s not found", r.From)
+			}
+			if _, ok := dcs[r.To]; ok {
+				return fmt.Errorf("data collection %s already exists", r.To)
+			}
+			delete(dcs, r.From)
+			dcs[r.To] = dc
+			// the foreign keys follow the renamed data collection, like in SQL databases.
+			for _, other := range dcs {
+				for i, fk := range other.foreignKeys {
+					if fk.ReferencedDataCollection == r.From {
+						other.foreignKeys[i].ReferencedDataCollection = r.To
+					}
+				}
+			}
+		}
+		c.db.dataCollections = dcs
+		return nil
+	})
+}
+
+func (c *memoryConnection) CopyDataCollection(_ context.Context, from, to string) error {
+	return c.ddl("copy", to, func() error {
+		src, ok := c.db.dataCollections[from]
+		if !ok {
+			return fmt.Errorf("data collection %s not found", from)
+		}
+		dest, ok := c.db.dataCollections[to]
+		if !ok {
+			return fmt.Errorf("data collection %s not found", to)
+		}
+		dest.sets = append(dest.sets, src.sets...)
+		return nil
+	})
+}
+
+func (c *memoryConnection) CountDataSets(_ context.Context, dataCollection string) (uint64, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	dc, ok := c.db.dataCollections[dataCollection]
+	if !ok {
+		return 0, fmt.Errorf("data collection %s not found", dataCollection)
+	}
+	return uint64(len(dc.sets)), nil
+}
+
+// newIDSet returns a data set with the given id.
+func newIDSet(t *testing.T, id int64) *data.Set {
+	t.Helper()
+	vt := &cockroach.IntType{}
+	vt.Init(vt)
+	if err := vt.Parse(&id); err != nil {
+		t.Fatal(err)
+	}
+	set := data.NewDataSet()
+	set.Add(data.NewData("id", vt))
+	return set
+}
+
+// newTestGLoader returns a GLoader from the src to the dest in-memory database, which doesn't log.
+func newTestGLoader(t *testing.T, src, dest *memoryDatabase) *GLoader {
+	t.Helper()
+	g := NewGLoader().SetLogger(log.NewLogger(log.NewHandler(io.Discard, io.Discard)))
+	if err := g.Src("memory", src.name); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Dest("memory", dest.name); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// wantIDs fails the test if the ids aren't 0 to length-1, in any order.
+func wantIDs(t *testing.T, ids []int64, length int) {
+	t.Helper()
+	sorted := append([]int64(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	if len(sorted) != length {
+		t.Fatalf("got %d data sets, want %d", len(sorted), length)
+	}
+	for i, id := range sorted {
+		if id != int64(i) {
+			t.Fatalf("got ids %v, want 0 to %d", sorted, length-1)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package gloader
+
+import (
+	"testing"
+
+	"github.com/mohammadv184/gloader/driver"
+)
+
+func TestDataCollectionOverrides(t *testing.T) {
+	g := NewGLoader().SetWorkers(3).SetRowsPerBatch(100)
+	g.SetDataCollectionWorkers("orders", 8).SetDataCollectionWriterWorkers("orders", 2)
+	g.SetDataCollectionReaderWorkers("events", 5)
+	g.SetDataCollectionRowsPerBatch("events", 10)
+
+	tests := []struct {
+		dataCollection                      string
+		readerWorkers, writerWorkers, conns uint
+		rowsPerBatch                        uint64
+	}{
+		{dataCollection: "users", readerWorkers: 3, writerWorkers: 3, conns: 6, rowsPerBatch: 100},
+		// the writer workers override the workers, whatever the order they're set in.
+		{dataCollection: "orders", readerWorkers: 8, writerWorkers: 2, conns: 10, rowsPerBatch: 100},
+		{dataCollection: "events", readerWorkers: 5, writerWorkers: 3, conns: 8, rowsPerBatch: 10},
+	}
+	for _, tt := range tests {
+		if got := g.readerWorkersOf(tt.dataCollection); got != tt.readerWorkers {
+			t.Errorf("readerWorkersOf(%s) = %d, want %d", tt.dataCollection, got, tt.readerWorkers)
+		}
+		if got := g.writerWorkersOf(tt.dataCollection); got != tt.writerWorkers {
+			t.Errorf("writerWorkersOf(%s) = %d, want %d", tt.dataCollection, got, tt.writerWorkers)
+		}
+		if got := g.connectionsOf(driver.DataCollectionDetail{Name: tt.dataCollection}); got != tt.conns {
+			t.Errorf("connectionsOf(%s) = %d, want %d", tt.dataCollection, got, tt.conns)
+		}
+		if got := g.rowsPerBatchOf(tt.dataCollection); got != tt.rowsPerBatch {
+			t.Errorf("rowsPerBatchOf(%s) = %d, want %d", tt.dataCollection, got, tt.rowsPerBatch)
+		}
+	}
+}
+
+func TestGLoaderUsesDataCollectionRowsPerBatch(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 100)
+	src.addDataCollection(t, "orders", 100)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 0)
+	dest.addDataCollection(t, "orders", 0)
+
+	g := newTestGLoader(t, src, dest).SetRowsPerBatch(50).SetWorkers(2)
+	g.SetDataCollectionRowsPerBatch("users", 7).SetDataCollectionWriterWorkers("users", 1)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, max := range map[string]uint64{"users": 7, "orders": 50} {
+		wantIDs(t, dest.ids(name), 100)
+		for _, n := range dest.writtenBatches(name) {
+			if n > max {
+				t.Errorf("written batch of %d data sets to %s, want at most %d", n, name, max)
+			}
+		}
+	}
+}
@@ -1,41 +1,81 @@
 package log
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
-	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
-	"text/template"
+	"time"
 
 	"github.com/fatih/color"
+	"golang.org/x/term"
 )
 
-var logTemplate = `{{- .time}} {{.level}} {{if .prefix}}[{{.prefix}}]{{end}} {{.message}} {{range .attrs}} {{.Key}}={{.Value.String}} {{end -}}
-{{range $i, $group := .groups}} {{range $j, $attr := index $.gAttrs $i}} {{"\n"}}---  {{- $group }}  {{$attr.Key -}} = {{- $attr.Value.String}} {{end}} {{end}}`
-
 const timeFormat = "2006-01-02 15:04:05"
 
-var levelStringColorsMap = map[slog.Level]string{
-	slog.LevelDebug: color.New(color.FgGreen, color.Bold).Sprint("DEBUG"),
-	slog.LevelInfo:  color.New(color.FgCyan, color.Bold).Sprint("INFO"),
-	slog.LevelWarn:  color.New(color.FgYellow, color.Bold).Sprint("WARN"),
-	slog.LevelError: color.New(color.FgRed, color.Bold, color.Underline).Sprint("ERROR"),
+// Format is the output format of a Handler.
+type Format uint8
+
+const (
+	// FormatText writes the records as human-readable lines,
+	// the attributes of the groups are written with the dotted group names as key prefix.
+	FormatText Format = iota
+	// FormatJSON writes the records as JSON objects, one per line,
+	// the groups are written as nested objects.
+	FormatJSON
+)
+
+var levelStringColorsMap = map[slog.Level]*color.Color{
+	slog.LevelDebug: color.New(color.FgGreen, color.Bold),
+	slog.LevelInfo:  color.New(color.FgCyan, color.Bold),
+	slog.LevelWarn:  color.New(color.FgYellow, color.Bold),
+	slog.LevelError: color.New(color.FgRed, color.Bold, color.Underline),
 }
 
+func init() {
+	// the color is enabled per handler, regardless of the stdout of the process.
+	for _, c := range levelStringColorsMap {
+		c.EnableColor()
+	}
+}
+
+// groupOrAttrs is a group opened by WithGroup, or the attributes added by WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// Handler is a slog.Handler that writes the records with the error level and above to stderr,
+// and the other records to stdout.
+//
+// The minimum level is shared by the handler and all the handlers derived from it,
+// so it can be changed at runtime with SetLevel. When the minimum level is debug or lower,
+// the source location of the log call is added to the records.
 type Handler struct {
 	stdout io.Writer
 	stderr io.Writer
 
 	prefix string
-	groups []string
-	gAttrs map[int][]slog.Attr
-	attrs  []slog.Attr
-	tmplt  *template.Template
+	level  *slog.LevelVar
+	format Format
+	color  bool
+	goas   []groupOrAttrs
 	mu     *sync.Mutex
 }
 
+var _ slog.Handler = &Handler{}
+
+// NewHandler returns a text handler with the info minimum level.
+// The level is colored when stdout is a terminal.
 func NewHandler(stdout, stderr io.Writer, prefix ...string) *Handler {
 	var p string
 	if len(prefix) > 0 {
@@ -45,14 +85,37 @@ func NewHandler(stdout, stderr io.Writer, prefix ...string) *Handler {
 		stdout: stdout,
 		stderr: stderr,
 		prefix: p,
-		gAttrs: make(map[int][]slog.Attr),
-		tmplt:  template.Must(template.New("log").Parse(logTemplate)),
+		level:  new(slog.LevelVar),
+		color:  isTerminal(stdout),
 		mu:     new(sync.Mutex),
 	}
 }
 
-func (*Handler) Enabled(_ context.Context, l slog.Level) bool {
-	return l >= slog.LevelInfo
+// SetLevel sets the minimum level of the handler and all the handlers derived from it.
+func (h *Handler) SetLevel(level slog.Level) *Handler {
+	h.level.Set(level)
+	return h
+}
+
+// Level returns the minimum level of the handler.
+func (h *Handler) Level() slog.Level {
+	return h.level.Level()
+}
+
+// SetFormat sets the output format of the handler.
+func (h *Handler) SetFormat(format Format) *Handler {
+	h.format = format
+	return h
+}
+
+// SetColor enables or disables the colored level of the text format.
+func (h *Handler) SetColor(enabled bool) *Handler {
+	h.color = enabled
+	return h
+}
+
+func (h *Handler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
 }
 
 func (h *Handler) WithPrefix(prefix string) slog.Handler {
@@ -62,72 +125,226 @@ func (h *Handler) WithPrefix(prefix string) slog.Handler {
 }
 
 func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
 	h2 := h.clone()
-	h2.groups = append(h2.groups, name)
-	h2.gAttrs[len(h2.groups)-1] = make([]slog.Attr, 0)
+	h2.goas = append(h2.goas, groupOrAttrs{group: name})
 	return h2
 }
 
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	h2 := h.clone()
-	if len(h2.groups) == 0 {
-		h2.attrs = append(h2.attrs, attrs...)
-
-	} else {
-		h2.gAttrs[len(h2.groups)-1] = append(h2.gAttrs[len(h2.groups)-1], attrs...)
+	if len(attrs) == 0 {
+		return h
 	}
+	h2 := h.clone()
+	h2.goas = append(h2.goas, groupOrAttrs{attrs: slices.Clone(attrs)})
 	return h2
 }
 
 func (h *Handler) Handle(_ context.Context, r slog.Record) error {
-	attrs := slices.Clone(h.attrs)
-	groups := slices.Clone(h.groups)
-	gAttrs := maps.Clone(h.gAttrs)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
 	r.Attrs(func(attr slog.Attr) bool {
-		if attr.Value.Kind() == slog.KindGroup {
-			groups = append(groups, attr.Key)
-			gAttrs[len(groups)-1] = slices.Clone(attr.Value.Group())
-			return true
-		}
-		if len(h.groups) > 0 {
-			gAttrs[len(h.groups)-1] = append(gAttrs[len(h.groups)-1], attr)
-			return true
-		}
 		attrs = append(attrs, attr)
 		return true
 	})
 
+	// nest the attributes of the record and the handler in the groups of the handler.
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		if h.goas[i].group == "" {
+			attrs = append(slices.Clone(h.goas[i].attrs), attrs...)
+			continue
+		}
+		if len(attrs) == 0 {
+			// empty groups are omitted.
+			continue
+		}
+		attrs = []slog.Attr{{Key: h.goas[i].group, Value: slog.GroupValue(attrs...)}}
+	}
+
+	var source string
+	if h.level.Level() <= slog.LevelDebug && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		source = filepath.Base(filepath.Dir(frame.File)) + "/" + filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+	}
+
+	buf := &bytes.Buffer{}
+	if h.format == FormatJSON {
+		h.writeJSON(buf, r, source, attrs)
+	} else {
+		h.writeText(buf, r, source, attrs)
+	}
+
+	out := h.stdout
 	if r.Level >= slog.LevelError {
-		return h.tmplt.Execute(h.stderr, map[string]interface{}{
-			"time":    r.Time.Format(timeFormat),
-			"level":   levelStringColorsMap[r.Level],
-			"prefix":  h.prefix,
-			"message": r.Message,
-			"attrs":   attrs,
-			"groups":  groups,
-			"gAttrs":  gAttrs,
-		})
-	}
-
-	return h.tmplt.Execute(h.stdout, map[string]interface{}{
-		"time":    r.Time.Format(timeFormat),
-		"level":   levelStringColorsMap[r.Level],
-		"prefix":  h.prefix,
-		"message": r.Message,
-		"attrs":   attrs,
-		"groups":  groups,
-		"gAttrs":  gAttrs,
-	})
+		out = h.stderr
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := out.Write(buf.Bytes())
+	return err
 }
+
+func (h *Handler) writeText(buf *bytes.Buffer, r slog.Record, source string, attrs []slog.Attr) {
+	if !r.Time.IsZero() {
+		buf.WriteString(r.Time.Format(timeFormat))
+		buf.WriteByte(' ')
+	}
+	level := r.Level.String()
+	if c, ok := levelStringColorsMap[r.Level]; ok && h.color {
+		level = c.Sprint(level)
+	}
+	buf.WriteString(level)
+	if h.prefix != "" {
+		buf.WriteString(" [" + h.prefix + "]")
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(r.Message)
+	writeTextAttrs(buf, "", attrs)
+	if source != "" {
+		buf.WriteString(" source=" + source)
+	}
+	buf.WriteByte('\n')
+}
+
+func writeTextAttrs(buf *bytes.Buffer, keyPrefix string, attrs []slog.Attr) {
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Equal(slog.Attr{}) {
+			continue
+		}
+		if attr.Value.Kind() == slog.KindGroup {
+			groupPrefix := keyPrefix
+			if attr.Key != "" {
+				groupPrefix += attr.Key + "."
+			}
+			writeTextAttrs(buf, groupPrefix, attr.Value.Group())
+			continue
+		}
+
+		buf.WriteString(" " + keyPrefix + attr.Key + "=")
+		v := textValue(attr.Value)
+		if v == "" || strings.ContainsAny(v, " =\"\n\t") {
+			v = strconv.Quote(v)
+		}
+		buf.WriteString(v)
+	}
+}
+
+func textValue(v slog.Value) string {
+	if v.Kind() == slog.KindTime {
+		return v.Time().Format(time.RFC3339)
+	}
+	return v.String()
+}
+
+func (h *Handler) writeJSON(buf *bytes.Buffer, r slog.Record, source string, attrs []slog.Attr) {
+	buf.WriteByte('{')
+	if !r.Time.IsZero() {
+		writeJSONField(buf, slog.TimeKey, r.Time.Format(time.RFC3339Nano))
+		buf.WriteByte(',')
+	}
+	writeJSONField(buf, slog.LevelKey, r.Level.String())
+	if h.prefix != "" {
+		buf.WriteByte(',')
+		writeJSONField(buf, "prefix", h.prefix)
+	}
+	buf.WriteByte(',')
+	writeJSONField(buf, slog.MessageKey, r.Message)
+	if source != "" {
+		buf.WriteByte(',')
+		writeJSONField(buf, slog.SourceKey, source)
+	}
+	writeJSONAttrs(buf, attrs, true)
+	buf.WriteString("}\n")
+}
+
+// writeJSONAttrs writes the attributes as the fields of a JSON object, each preceded by a comma
+// unless it's the first field and leadingComma is false. It reports whether any field is written.
+func writeJSONAttrs(buf *bytes.Buffer, attrs []slog.Attr, leadingComma bool) bool {
+	written := false
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Equal(slog.Attr{}) {
+			continue
+		}
+		if attr.Value.Kind() == slog.KindGroup && attr.Key == "" {
+			// the attributes of a group without key are inlined.
+			if writeJSONAttrs(buf, attr.Value.Group(), leadingComma || written) {
+				written = true
+			}
+			continue
+		}
+		if attr.Value.Kind() == slog.KindGroup && len(attr.Value.Group()) == 0 {
+			continue
+		}
+
+		if leadingComma || written {
+			buf.WriteByte(',')
+		}
+		written = true
+
+		if attr.Value.Kind() == slog.KindGroup {
+			writeJSONString(buf, attr.Key)
+			buf.WriteString(":{")
+			writeJSONAttrs(buf, attr.Value.Group(), false)
+			buf.WriteByte('}')
+			continue
+		}
+		writeJSONField(buf, attr.Key, jsonValue(attr.Value))
+	}
+	return written
+}
+
+func jsonValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		switch a := v.Any().(type) {
+		case error:
+			return a.Error()
+		case json.Marshaler:
+			return a
+		case fmt.Stringer:
+			return a.String()
+		}
+	}
+	return v.Any()
+}
+
+func writeJSONField(buf *bytes.Buffer, key string, value any) {
+	writeJSONString(buf, key)
+	buf.WriteByte(':')
+	b, err := json.Marshal(value)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprintf("!ERROR:%v", err))
+	}
+	buf.Write(b)
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
 func (h *Handler) clone() *Handler {
 	return &Handler{
 		stdout: h.stdout,
 		stderr: h.stderr,
 		prefix: h.prefix,
-		groups: slices.Clone(h.groups),
-		gAttrs: maps.Clone(h.gAttrs),
-		attrs:  slices.Clone(h.attrs),
-		tmplt:  h.tmplt,
+		level:  h.level, // level shared among all clones of this handler
+		format: h.format,
+		color:  h.color,
+		goas:   slices.Clip(h.goas),
 		mu:     h.mu, // mutex shared among all clones of this handler
 	}
 }
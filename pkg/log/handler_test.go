@@ -0,0 +1,142 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func newTestHandler() (*Handler, *bytes.Buffer, *bytes.Buffer) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	return NewHandler(stdout, stderr, "test"), stdout, stderr
+}
+
+// stripTime removes the leading date and time of a text line.
+func stripTime(line string) string {
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) < 3 {
+		return line
+	}
+	return fields[2]
+}
+
+func TestHandlerText(t *testing.T) {
+	h, stdout, stderr := newTestHandler()
+	l := NewLogger(h)
+	l.Info("loading", "table", "users", "rows", 10, "query", "SELECT 1")
+	l.Error("failed", Err(errors.New("boom")))
+
+	if got, want := stripTime(stdout.String()), "INFO [test] loading table=users rows=10 query=\"SELECT 1\"\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	// the errors are written to stderr.
+	if got, want := stripTime(stderr.String()), "ERROR [test] failed err=boom\n"; got != want {
+		t.Errorf("stderr = %q, want %q", got, want)
+	}
+}
+
+func TestHandlerLevel(t *testing.T) {
+	h, stdout, _ := newTestHandler()
+	l := NewLogger(h)
+	child := l.WithPrefix("users")
+
+	l.Debug("hidden")
+	if stdout.Len() != 0 {
+		t.Fatalf("a debug record is written at the info level: %q", stdout.String())
+	}
+
+	// the level is shared with the derived handlers, and changed at runtime.
+	h.SetLevel(slog.LevelDebug)
+	child.Debug("shown")
+	line := stripTime(stdout.String())
+	if !strings.HasPrefix(line, "DEBUG [users] shown source=log/handler_test.go:") {
+		t.Errorf("debug line = %q, want the prefix and the source location", line)
+	}
+
+	stdout.Reset()
+	h.SetLevel(slog.LevelWarn)
+	child.Info("hidden")
+	if stdout.Len() != 0 || h.Level() != slog.LevelWarn {
+		t.Errorf("an info record is written at the warn level: %q", stdout.String())
+	}
+}
+
+func TestHandlerGroupsAndAttrs(t *testing.T) {
+	h, stdout, _ := newTestHandler()
+	l := slog.New(h).With("run", 1).WithGroup("table").With("name", "users").WithGroup("batch")
+	l.Info("written", "rows", 10, slog.Group("latency", "ms", 5))
+	// the empty groups are omitted.
+	slog.New(h).WithGroup("empty").Info("nothing")
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if got, want := stripTime(lines[0]), "INFO [test] written run=1 table.name=users table.batch.rows=10 table.batch.latency.ms=5"; got != want {
+		t.Errorf("text line = %q, want %q", got, want)
+	}
+	if got, want := stripTime(lines[1]), "INFO [test] nothing"; got != want {
+		t.Errorf("text line = %q, want %q", got, want)
+	}
+}
+
+func TestHandlerJSON(t *testing.T) {
+	h, stdout, _ := newTestHandler()
+	h.SetFormat(FormatJSON)
+	l := slog.New(h).With("run", 1).WithGroup("table").With("name", "users")
+	l.Info("written", "rows", 10, "err", errors.New("boom"), slog.Group("empty"))
+
+	var got map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", stdout.String(), err)
+	}
+	delete(got, "time")
+	want := map[string]any{
+		"level":  "INFO",
+		"prefix": "test",
+		"msg":    "written",
+		"run":    1.0,
+		"table": map[string]any{
+			"name": "users",
+			"rows": 10.0,
+			"err":  "boom",
+		},
+	}
+	if gotJSON, wantJSON := mustJSON(t, got), mustJSON(t, want); gotJSON != wantJSON {
+		t.Errorf("JSON line = %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+func TestHandlerColor(t *testing.T) {
+	h, stdout, _ := newTestHandler()
+	NewLogger(h).Info("plain")
+	if strings.Contains(stdout.String(), "\x1b[") {
+		t.Errorf("the output of a non-terminal is colored: %q", stdout.String())
+	}
+
+	stdout.Reset()
+	NewLogger(h.SetColor(true)).Info("colored")
+	if !strings.Contains(stdout.String(), "\x1b[") {
+		t.Errorf("the output isn't colored: %q", stdout.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLevel(name); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) didn't fail")
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
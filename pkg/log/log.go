@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"runtime"
 	"time"
 )
 
@@ -11,12 +12,45 @@ type Logger struct {
 	*slog.Logger
 }
 
-func NewLogger(handler *Handler) *Logger {
+func NewLogger(handler slog.Handler) *Logger {
 	return &Logger{slog.New(handler)}
 }
 
+// WithPrefix returns a logger whose records are prefixed with the given prefix.
+// If the handler isn't a Handler, the prefix is added as the prefix attribute.
 func (l *Logger) WithPrefix(prefix string) *Logger {
-	return &Logger{slog.New(l.Logger.Handler().(*Handler).WithPrefix(prefix))}
+	if h, ok := l.Logger.Handler().(*Handler); ok {
+		return &Logger{slog.New(h.WithPrefix(prefix))}
+	}
+	return &Logger{l.Logger.With(String("prefix", prefix))}
+}
+
+// Fatal logs the message at the error level and exits the program.
+func (l *Logger) Fatal(msg string, args ...any) {
+	l.log(slog.LevelError, msg, args...)
+	os.Exit(1)
+}
+
+// log logs the record with the source location of the caller of its caller,
+// so the wrappers of the logger don't hide the location of the log call.
+func (l *Logger) log(level slog.Level, msg string, args ...any) {
+	ctx := context.Background()
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	// skip runtime.Callers, log, and the wrapper.
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = l.Handler().Handle(ctx, r)
+}
+
+// ParseLevel returns the level of the given name (debug, info, warn, error).
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(level))
+	return l, err
 }
 
 var defaultLogger = NewLogger(NewHandler(os.Stdout, os.Stderr, "GLoader"))
@@ -38,28 +72,28 @@ func WithGroup(name string) *slog.Logger {
 }
 
 func Info(msg string, args ...any) {
-	Log(slog.LevelInfo, msg, args...)
+	defaultLogger.log(slog.LevelInfo, msg, args...)
 }
 
 func Debug(msg string, args ...any) {
-	Log(slog.LevelDebug, msg, args...)
+	defaultLogger.log(slog.LevelDebug, msg, args...)
 }
 
 func Warn(msg string, args ...any) {
-	Log(slog.LevelWarn, msg, args...)
+	defaultLogger.log(slog.LevelWarn, msg, args...)
 }
 
 func Error(msg string, args ...any) {
-	Log(slog.LevelError, msg, args...)
+	defaultLogger.log(slog.LevelError, msg, args...)
 }
 
 func Fatal(msg string, args ...any) {
-	Log(slog.LevelError, msg, args...)
+	defaultLogger.log(slog.LevelError, msg, args...)
 	os.Exit(1)
 }
 
 func Log(level slog.Level, msg string, args ...any) {
-	defaultLogger.Log(context.Background(), level, msg, args...)
+	defaultLogger.log(level, msg, args...)
 }
 
 // Attributes wrapper funcs
@@ -88,6 +122,11 @@ func Bool(key string, v bool) slog.Attr {
 	return slog.Bool(key, v)
 }
 
+// Err returns the attribute of an error.
+func Err(err error) slog.Attr {
+	return slog.Any("err", err)
+}
+
 func Time(key string, v time.Time) slog.Attr {
 	return slog.Time(key, v)
 }
@@ -0,0 +1,103 @@
+// Package ratelimit provides a token bucket rate limiter whose rate can be changed at runtime.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket rate limiter.
+// The bucket is refilled at the rate per second, and holds up to one second worth of tokens.
+// Requests larger than the bucket are allowed, and the following requests wait until the debt is paid.
+// A zero rate means unlimited. It is safe for concurrent use.
+type Limiter struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+	mu     *sync.Mutex
+}
+
+// New returns a new limiter with the given rate per second.
+func New(rate float64) *Limiter {
+	return &Limiter{
+		rate:   rate,
+		tokens: rate,
+		last:   time.Now(),
+		mu:     &sync.Mutex{},
+	}
+}
+
+// Rate returns the current rate per second.
+func (l *Limiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// SetRate changes the rate per second. A zero rate means unlimited.
+// The new rate applies to the next requests.
+func (l *Limiter) SetRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = rate
+	l.tokens = math.Min(l.tokens, rate)
+}
+
+// WaitN blocks until n tokens are taken from the bucket, or the context is done.
+func (l *Limiter) WaitN(ctx context.Context, n uint64) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	l.refill(now)
+	l.tokens -= float64(n)
+
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// refill adds the tokens earned since the last refill. The caller must hold the lock.
+func (l *Limiter) refill(now time.Time) {
+	if l.rate > 0 {
+		l.tokens = math.Min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+}
+
+// Limiters is a group of limiters that are all applied to the same requests.
+type Limiters []*Limiter
+
+// WaitN blocks until n tokens are taken from every limiter of the group, or the context is done.
+func (ls Limiters) WaitN(ctx context.Context, n uint64) error {
+	for _, l := range ls {
+		if l == nil {
+			continue
+		}
+		if err := l.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
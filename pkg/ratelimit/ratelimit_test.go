@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterUnlimited(t *testing.T) {
+	l := New(0)
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		if err := l.WaitN(context.Background(), 1000000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("unlimited WaitN took %v", elapsed)
+	}
+}
+
+func TestLimiterWaitsForDebt(t *testing.T) {
+	l := New(100)
+	start := time.Now()
+	// the bucket starts full, so the first 100 tokens are taken at once.
+	if err := l.WaitN(context.Background(), 100); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("WaitN of a full bucket took %v", elapsed)
+	}
+
+	// a request larger than the bucket is allowed, and it waits until its debt is paid.
+	if err := l.WaitN(context.Background(), 20); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("WaitN of 20 tokens over an empty bucket of 100/s took %v, want about 200ms", elapsed)
+	}
+}
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	l := New(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.WaitN(ctx, 100); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitN() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLimiterSetRate(t *testing.T) {
+	l := New(1)
+	if err := l.WaitN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	l.SetRate(1000)
+	if got := l.Rate(); got != 1000 {
+		t.Errorf("Rate() = %v, want 1000", got)
+	}
+	start := time.Now()
+	if err := l.WaitN(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("WaitN after raising the rate took %v", elapsed)
+	}
+
+	// lowering the rate shrinks the bucket, so the saved tokens can't burst over the new rate.
+	l.SetRate(10)
+	start = time.Now()
+	if err := l.WaitN(context.Background(), 12); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("WaitN after lowering the rate took %v, want about 200ms", elapsed)
+	}
+
+	l.SetRate(0)
+	start = time.Now()
+	if err := l.WaitN(context.Background(), 1000); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("WaitN after removing the rate took %v", elapsed)
+	}
+}
+
+func TestLimitersWaitForEveryLimiter(t *testing.T) {
+	ls := Limiters{New(0), nil, New(100)}
+	if err := ls.WaitN(context.Background(), 100); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := ls.WaitN(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("WaitN of the group took %v, want about 100ms of the slowest limiter", elapsed)
+	}
+}
@@ -0,0 +1,175 @@
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the default histogram buckets for latencies in seconds.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Histogram is a metric that samples observations into buckets.
+// Its Value is the number of observations.
+type Histogram interface {
+	Metric
+	// Observe adds the given value to the histogram.
+	Observe(value float64, tags ...string)
+	// ObserveDuration adds the given duration in seconds to the histogram.
+	ObserveDuration(d time.Duration, tags ...string)
+	// Time starts a timer, and returns a function that observes the elapsed duration when it's called.
+	Time(tags ...string) func()
+	// Buckets returns the upper bounds of the buckets in ascending order.
+	Buckets() []float64
+	// BucketCounts returns the cumulative number of observations of each bucket.
+	BucketCounts(tags ...string) []uint64
+	// Sum returns the sum of all observations.
+	Sum(tags ...string) float64
+}
+
+// DefaultHistogram is a Histogram that is safe for concurrent use.
+type DefaultHistogram struct {
+	buckets       []float64
+	counts        []uint64
+	count         uint64
+	sum           float64
+	mu            *sync.RWMutex
+	tagHistograms *tagMap[*DefaultHistogram]
+	subscribers   *subscribers
+}
+
+// NewDefaultHistogram returns a new histogram with the given bucket upper bounds.
+// If no bucket is given, the DefaultLatencyBuckets are used.
+func NewDefaultHistogram(buckets ...float64) *DefaultHistogram {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	sort.Float64s(b)
+
+	return &DefaultHistogram{
+		buckets: b,
+		counts:  make([]uint64, len(b)),
+		mu:      &sync.RWMutex{},
+		tagHistograms: newTagMap(func() *DefaultHistogram {
+			return NewDefaultHistogram(b...)
+		}),
+		subscribers: newSubscribers(),
+	}
+}
+
+func (h *DefaultHistogram) Tags() []string {
+	return h.tagHistograms.keys()
+}
+
+func (h *DefaultHistogram) Value(tags ...string) int64 {
+	if len(tags) > 0 {
+		var count int64
+		for _, tag := range tags {
+			count += h.tag(tag).Value()
+		}
+		return count
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return int64(h.count)
+}
+
+func (h *DefaultHistogram) NotifyOnChange(ch chan<- any, tags ...string) {
+	if len(tags) > 0 {
+		for _, tag := range tags {
+			h.tag(tag).NotifyOnChange(ch)
+		}
+		return
+	}
+
+	h.subscribers.add(ch)
+}
+
+func (h *DefaultHistogram) Unsubscribe(ch chan<- any, tags ...string) {
+	if len(tags) > 0 {
+		for _, tag := range tags {
+			h.tag(tag).Unsubscribe(ch)
+		}
+		return
+	}
+
+	h.subscribers.remove(ch)
+}
+
+func (h *DefaultHistogram) Observe(value float64, tags ...string) {
+	if len(tags) > 0 {
+		for _, tag := range tags {
+			h.tag(tag).Observe(value)
+		}
+		return
+	}
+
+	h.mu.Lock()
+	i := sort.SearchFloat64s(h.buckets, value)
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += value
+	h.mu.Unlock()
+
+	h.subscribers.notify()
+}
+
+func (h *DefaultHistogram) ObserveDuration(d time.Duration, tags ...string) {
+	h.Observe(d.Seconds(), tags...)
+}
+
+func (h *DefaultHistogram) Time(tags ...string) func() {
+	start := time.Now()
+	return func() {
+		h.ObserveDuration(time.Since(start), tags...)
+	}
+}
+
+func (h *DefaultHistogram) Buckets() []float64 {
+	return h.buckets
+}
+
+func (h *DefaultHistogram) BucketCounts(tags ...string) []uint64 {
+	cumulative := make([]uint64, len(h.buckets))
+	if len(tags) > 0 {
+		for _, tag := range tags {
+			for i, c := range h.tag(tag).BucketCounts() {
+				cumulative[i] += c
+			}
+		}
+		return cumulative
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var count uint64
+	for i, c := range h.counts {
+		count += c
+		cumulative[i] = count
+	}
+	return cumulative
+}
+
+func (h *DefaultHistogram) Sum(tags ...string) float64 {
+	if len(tags) > 0 {
+		var sum float64
+		for _, tag := range tags {
+			sum += h.tag(tag).Sum()
+		}
+		return sum
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.sum
+}
+
+// tag returns the histogram of the given tag, and creates it if it doesn't exist.
+func (h *DefaultHistogram) tag(tag string) *DefaultHistogram {
+	return h.tagHistograms.get(tag)
+}
@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	h := NewDefaultHistogram(1, 0.1, 10)
+	if got, want := h.Buckets(), []float64{0.1, 1, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Buckets() = %v, want %v", got, want)
+	}
+
+	for _, v := range []float64{0.05, 0.1, 0.5, 5, 50} {
+		h.Observe(v)
+	}
+	// the buckets are cumulative and their upper bounds are inclusive.
+	if got, want := h.BucketCounts(), []uint64{2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("BucketCounts() = %v, want %v", got, want)
+	}
+	if got := h.Value(); got != 5 {
+		t.Errorf("Value() = %d, want 5", got)
+	}
+	if got := h.Sum(); got != 55.65 {
+		t.Errorf("Sum() = %v, want 55.65", got)
+	}
+}
+
+func TestHistogramTags(t *testing.T) {
+	h := NewDefaultHistogram(1, 10)
+	h.Observe(0.5, "users")
+	h.Observe(5, "users", "orders")
+	h.ObserveDuration(2*time.Second, "orders")
+
+	if got, want := h.BucketCounts("users"), []uint64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("BucketCounts(users) = %v, want %v", got, want)
+	}
+	if got, want := h.BucketCounts("users", "orders"), []uint64{1, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("BucketCounts(users, orders) = %v, want %v", got, want)
+	}
+	if got := h.Value("orders"); got != 2 {
+		t.Errorf("Value(orders) = %d, want 2", got)
+	}
+	if got := h.Sum("orders"); got != 7 {
+		t.Errorf("Sum(orders) = %v, want 7", got)
+	}
+	// the tagged observations aren't observed untagged.
+	if got := h.Value(); got != 0 {
+		t.Errorf("Value() = %d, want 0", got)
+	}
+}
+
+func TestHistogramTime(t *testing.T) {
+	h := NewDefaultHistogram()
+	if got := h.Buckets(); !reflect.DeepEqual(got, DefaultLatencyBuckets) {
+		t.Errorf("Buckets() = %v, want %v", got, DefaultLatencyBuckets)
+	}
+
+	ch := make(chan any, 1)
+	h.NotifyOnChange(ch)
+	stop := h.Time()
+	time.Sleep(10 * time.Millisecond)
+	stop()
+
+	if got := h.Sum(); got < 0.01 {
+		t.Errorf("Sum() = %v, want at least 0.01", got)
+	}
+	select {
+	case <-ch:
+	default:
+		t.Error("the subscriber wasn't notified of the observation")
+	}
+}
+
+func TestPrometheusExporterHistogram(t *testing.T) {
+	s := New()
+	s.RegisterHistogram("writer.latency.seconds", "write latency", 0.1, 1).Observe(0.5, "users")
+
+	var b strings.Builder
+	if err := NewPrometheusExporter(s.Registry()).WithTagLabel("table").Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP gloader_writer_latency_seconds write latency
+# TYPE gloader_writer_latency_seconds histogram
+gloader_writer_latency_seconds_bucket{table="users",le="0.1"} 0
+gloader_writer_latency_seconds_bucket{table="users",le="1"} 1
+gloader_writer_latency_seconds_bucket{table="users",le="+Inf"} 1
+gloader_writer_latency_seconds_sum{table="users"} 0.5
+gloader_writer_latency_seconds_count{table="users"} 1
+`
+	if got := b.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -10,8 +10,12 @@ type Metric interface {
 	Tags() []string
 	// Value returns the current value of the counter.
 	Value(tags ...string) int64
-	// NotifyOnChange receives a channel that will be notified when the value of the counter changes.
+	// NotifyOnChange subscribes a channel that will be notified when the value of the counter changes.
+	// A metric can have multiple subscribers. The notifications don't block,
+	// so a subscriber that isn't ready to receive misses the notification.
 	NotifyOnChange(ch chan<- any, tags ...string)
+	// Unsubscribe stops notifying the channel subscribed by NotifyOnChange.
+	Unsubscribe(ch chan<- any, tags ...string)
 }
 
 // SequentialCounter is a counter that can only be incremented.
@@ -38,28 +42,22 @@ type GaugeCounter interface {
 	DecBy(delta int64, tags ...string) int64
 }
 
+// DefaultSequentialCounter is a SequentialCounter that is safe for concurrent use.
 type DefaultSequentialCounter struct {
 	count          *uint64
-	tagCountersMap map[string]SequentialCounter
-	notifyCh       chan<- any
+	tagCountersMap *tagMap[SequentialCounter]
+	subscribers    *subscribers
 }
 
 func (c *DefaultSequentialCounter) Tags() []string {
-	tags := make([]string, 0, len(c.tagCountersMap))
-	for tag := range c.tagCountersMap {
-		tags = append(tags, tag)
-	}
-	return tags
+	return c.tagCountersMap.keys()
 }
 
 func (c *DefaultSequentialCounter) Value(tags ...string) int64 {
 	if len(tags) > 0 {
 		var count int64
 		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultSequentialCounter()
-			}
-			count += c.tagCountersMap[tag].Value()
+			count += c.tagCountersMap.get(tag).Value()
 		}
 		return count
 	}
@@ -70,91 +68,69 @@ func (c *DefaultSequentialCounter) Value(tags ...string) int64 {
 func (c *DefaultSequentialCounter) NotifyOnChange(ch chan<- any, tags ...string) {
 	if len(tags) > 0 {
 		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultSequentialCounter()
-			}
-			c.tagCountersMap[tag].NotifyOnChange(ch)
+			c.tagCountersMap.get(tag).NotifyOnChange(ch)
 		}
 		return
 	}
 
-	c.notifyCh = ch
+	c.subscribers.add(ch)
 }
 
-func (c *DefaultSequentialCounter) Inc(tags ...string) int64 {
+func (c *DefaultSequentialCounter) Unsubscribe(ch chan<- any, tags ...string) {
 	if len(tags) > 0 {
-		var count int64
 		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultSequentialCounter()
-			}
-			count += c.tagCountersMap[tag].Inc()
+			c.tagCountersMap.get(tag).Unsubscribe(ch)
 		}
-		return count
+		return
 	}
 
-	cv := int64(atomic.AddUint64(c.count, 1))
-	c.notify(c.notifyCh)
-	return cv
+	c.subscribers.remove(ch)
+}
+
+func (c *DefaultSequentialCounter) Inc(tags ...string) int64 {
+	return c.IncBy(1, tags...)
 }
 
 func (c *DefaultSequentialCounter) IncBy(delta int64, tags ...string) int64 {
 	if len(tags) > 0 {
 		var count int64
 		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultSequentialCounter()
-			}
-			count += c.tagCountersMap[tag].IncBy(delta)
+			count += c.tagCountersMap.get(tag).IncBy(delta)
 		}
 		return count
 	}
 
 	cv := int64(atomic.AddUint64(c.count, uint64(delta)))
-	c.notify(c.notifyCh)
+	c.subscribers.notify()
 	return cv
 }
 
-func (c *DefaultSequentialCounter) notify(ch chan<- any) {
-	if ch == nil {
-		return
-	}
-
-	select {
-	case ch <- "notify":
-	default:
-	}
-}
-
 func NewDefaultSequentialCounter() *DefaultSequentialCounter {
 	return &DefaultSequentialCounter{
-		count:          new(uint64),
-		tagCountersMap: make(map[string]SequentialCounter),
+		count: new(uint64),
+		tagCountersMap: newTagMap(func() SequentialCounter {
+			return NewDefaultSequentialCounter()
+		}),
+		subscribers: newSubscribers(),
 	}
 }
 
+// DefaultGaugeCounter is a GaugeCounter that is safe for concurrent use.
 type DefaultGaugeCounter struct {
 	count          *int64
-	tagCountersMap map[string]GaugeCounter
-	notifyCh       chan<- any
+	tagCountersMap *tagMap[GaugeCounter]
+	subscribers    *subscribers
 }
 
 func (c *DefaultGaugeCounter) Tags() []string {
-	tags := make([]string, 0, len(c.tagCountersMap))
-	for tag := range c.tagCountersMap {
-		tags = append(tags, tag)
-	}
-	return tags
+	return c.tagCountersMap.keys()
 }
 
 func (c *DefaultGaugeCounter) Value(tags ...string) int64 {
 	if len(tags) > 0 {
 		var count int64
 		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultGaugeCounter()
-			}
-			count += c.tagCountersMap[tag].Value()
+			count += c.tagCountersMap.get(tag).Value()
 		}
 		return count
 	}
@@ -165,113 +141,69 @@ func (c *DefaultGaugeCounter) Value(tags ...string) int64 {
 func (c *DefaultGaugeCounter) NotifyOnChange(ch chan<- any, tags ...string) {
 	if len(tags) > 0 {
 		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultGaugeCounter()
-			}
-			c.tagCountersMap[tag].NotifyOnChange(ch)
+			c.tagCountersMap.get(tag).NotifyOnChange(ch)
 		}
 		return
 	}
 
-	c.notifyCh = ch
+	c.subscribers.add(ch)
 }
 
-func (c *DefaultGaugeCounter) Set(value int64, tags ...string) {
+func (c *DefaultGaugeCounter) Unsubscribe(ch chan<- any, tags ...string) {
 	if len(tags) > 0 {
 		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultGaugeCounter()
-			}
-			c.tagCountersMap[tag].Set(value)
+			c.tagCountersMap.get(tag).Unsubscribe(ch)
 		}
+		return
 	}
 
-	atomic.StoreInt64(c.count, value)
-	c.notify(c.notifyCh)
+	c.subscribers.remove(ch)
 }
 
-func (c *DefaultGaugeCounter) IncBy(delta int64, tags ...string) int64 {
+func (c *DefaultGaugeCounter) Set(value int64, tags ...string) {
 	if len(tags) > 0 {
-		var count int64
 		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultGaugeCounter()
-			}
-			count += c.tagCountersMap[tag].IncBy(delta)
+			c.tagCountersMap.get(tag).Set(value)
 		}
-		return count
+		return
 	}
 
-	cv := atomic.AddInt64(c.count, delta)
-	c.notify(c.notifyCh)
-	return cv
+	atomic.StoreInt64(c.count, value)
+	c.subscribers.notify()
 }
 
-func (c *DefaultGaugeCounter) DecBy(delta int64, tags ...string) int64 {
+func (c *DefaultGaugeCounter) IncBy(delta int64, tags ...string) int64 {
 	if len(tags) > 0 {
 		var count int64
 		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultGaugeCounter()
-			}
-			count += c.tagCountersMap[tag].DecBy(delta)
+			count += c.tagCountersMap.get(tag).IncBy(delta)
 		}
 		return count
 	}
 
-	cv := atomic.AddInt64(c.count, -delta)
-	c.notify(c.notifyCh)
+	cv := atomic.AddInt64(c.count, delta)
+	c.subscribers.notify()
 	return cv
 }
 
-func (c *DefaultGaugeCounter) Inc(tags ...string) int64 {
-	if len(tags) > 0 {
-		var count int64
-		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultGaugeCounter()
-			}
-			count += c.tagCountersMap[tag].Inc()
-		}
-		return count
-	}
-
-	cv := atomic.AddInt64(c.count, 1)
-	c.notify(c.notifyCh)
-	return cv
+func (c *DefaultGaugeCounter) DecBy(delta int64, tags ...string) int64 {
+	return c.IncBy(-delta, tags...)
 }
 
-func (c *DefaultGaugeCounter) Dec(tags ...string) int64 {
-	if len(tags) > 0 {
-		var count int64
-		for _, tag := range tags {
-			if _, isExists := c.tagCountersMap[tag]; !isExists {
-				c.tagCountersMap[tag] = NewDefaultGaugeCounter()
-			}
-			count += c.tagCountersMap[tag].Dec()
-		}
-		return count
-	}
-
-	cv := atomic.AddInt64(c.count, -1)
-	c.notify(c.notifyCh)
-	return cv
+func (c *DefaultGaugeCounter) Inc(tags ...string) int64 {
+	return c.IncBy(1, tags...)
 }
 
-func (c *DefaultGaugeCounter) notify(ch chan<- any) {
-	if ch == nil {
-		return
-	}
-
-	select {
-	case ch <- "notify":
-	default:
-	}
+func (c *DefaultGaugeCounter) Dec(tags ...string) int64 {
+	return c.IncBy(-1, tags...)
 }
 
 func NewDefaultGaugeCounter() *DefaultGaugeCounter {
 	return &DefaultGaugeCounter{
-		count:          new(int64),
-		tagCountersMap: make(map[string]GaugeCounter),
+		count: new(int64),
+		tagCountersMap: newTagMap(func() GaugeCounter {
+			return NewDefaultGaugeCounter()
+		}),
+		subscribers: newSubscribers(),
 	}
 }
@@ -0,0 +1,167 @@
+package stats
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultPrometheusNamespace is the default prefix of the exported metric names.
+const DefaultPrometheusNamespace = "gloader"
+
+// DefaultPrometheusTagLabel is the default label name of the metric tags.
+const DefaultPrometheusTagLabel = "tag"
+
+// PrometheusExporter exposes the metrics of a registry in the Prometheus text exposition format.
+// SequentialCounters are exported as counters and GaugeCounters as gauges,
+// the metric descriptions are used as help texts, and each tag is exported as a separate series
+// with the tag as the value of the tag label.
+type PrometheusExporter struct {
+	registry  Registry
+	namespace string
+	tagLabel  string
+}
+
+// NewPrometheusExporter returns a new Prometheus exporter of the given registry.
+func NewPrometheusExporter(registry Registry) *PrometheusExporter {
+	return &PrometheusExporter{
+		registry:  registry,
+		namespace: DefaultPrometheusNamespace,
+		tagLabel:  DefaultPrometheusTagLabel,
+	}
+}
+
+// WithNamespace sets the prefix of the exported metric names.
+func (e *PrometheusExporter) WithNamespace(namespace string) *PrometheusExporter {
+	e.namespace = namespace
+	return e
+}
+
+// WithTagLabel sets the label name of the metric tags. e.g. "table".
+func (e *PrometheusExporter) WithTagLabel(label string) *PrometheusExporter {
+	e.tagLabel = label
+	return e
+}
+
+// ServeHTTP writes the metrics to the response.
+func (e *PrometheusExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = e.Write(w)
+}
+
+// Write writes the metrics to the given writer.
+func (e *PrometheusExporter) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, name := range e.registry.Names() {
+		m, err := e.registry.GetMetric(name)
+		if err != nil {
+			continue
+		}
+
+		// GaugeCounter must be checked first, since it has all the methods of a SequentialCounter.
+		var metricType string
+		switch m.(type) {
+		case Histogram:
+			metricType = "histogram"
+		case GaugeCounter:
+			metricType = "gauge"
+		case SequentialCounter:
+			metricType = "counter"
+		default:
+			metricType = "untyped"
+		}
+
+		pName := e.metricName(name, metricType == "counter")
+		if description, err := e.registry.Description(name); err == nil && description != "" {
+			bw.WriteString("# HELP " + pName + " " + escapeHelp(description) + "\n")
+		}
+		bw.WriteString("# TYPE " + pName + " " + metricType + "\n")
+
+		tags := m.Tags()
+		sort.Strings(tags)
+
+		if h, ok := m.(Histogram); ok {
+			if len(tags) == 0 {
+				e.writeHistogram(bw, pName, "", h)
+				continue
+			}
+			for _, tag := range tags {
+				e.writeHistogram(bw, pName, e.tagLabel+"=\""+escapeLabelValue(tag)+"\"", h, tag)
+			}
+			continue
+		}
+
+		if len(tags) == 0 {
+			bw.WriteString(pName + " " + strconv.FormatInt(m.Value(), 10) + "\n")
+			continue
+		}
+
+		for _, tag := range tags {
+			bw.WriteString(pName + "{" + e.tagLabel + "=\"" + escapeLabelValue(tag) + "\"} " + strconv.FormatInt(m.Value(tag), 10) + "\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// writeHistogram writes the buckets, sum, and count series of a histogram with the given labels.
+func (e *PrometheusExporter) writeHistogram(bw *bufio.Writer, pName, labels string, h Histogram, tags ...string) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+
+	for i, c := range h.BucketCounts(tags...) {
+		le := strconv.FormatFloat(h.Buckets()[i], 'g', -1, 64)
+		bw.WriteString(pName + "_bucket{" + labels + sep + "le=\"" + le + "\"} " + strconv.FormatUint(c, 10) + "\n")
+	}
+	count := strconv.FormatInt(h.Value(tags...), 10)
+	bw.WriteString(pName + "_bucket{" + labels + sep + "le=\"+Inf\"} " + count + "\n")
+
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	bw.WriteString(pName + "_sum" + labels + " " + strconv.FormatFloat(h.Sum(tags...), 'g', -1, 64) + "\n")
+	bw.WriteString(pName + "_count" + labels + " " + count + "\n")
+}
+
+// metricName converts a metric name like "buffer.totalReadLength.rows" to "gloader_buffer_total_read_length_rows".
+func (e *PrometheusExporter) metricName(name string, isCounter bool) string {
+	var b strings.Builder
+	if e.namespace != "" {
+		b.WriteString(e.namespace)
+		b.WriteByte('_')
+	}
+
+	var prev rune
+	for _, r := range name {
+		switch {
+		case unicode.IsUpper(r):
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+		prev = r
+	}
+
+	if isCounter && !strings.HasSuffix(b.String(), "_total") {
+		b.WriteString("_total")
+	}
+	return b.String()
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
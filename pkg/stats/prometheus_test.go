@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusExporterWrite(t *testing.T) {
+	s := New()
+	s.RegisterSequentialCounter("writer.totalWrittenRows", "The total number of written rows.").IncBy(7, "users")
+	s.MustGetSequentialCounter("writer.totalWrittenRows").IncBy(3, `a"b`)
+	s.RegisterGaugeCounter("buffer.length", "The number of data sets\nin the buffer.").Set(5)
+	s.RegisterSequentialCounter("run.retries_total", "").Inc()
+
+	var b strings.Builder
+	if err := NewPrometheusExporter(s.Registry()).WithTagLabel("table").Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP gloader_buffer_length The number of data sets\nin the buffer.
+# TYPE gloader_buffer_length gauge
+gloader_buffer_length 5
+# TYPE gloader_run_retries_total counter
+gloader_run_retries_total 1
+# HELP gloader_writer_total_written_rows_total The total number of written rows.
+# TYPE gloader_writer_total_written_rows_total counter
+gloader_writer_total_written_rows_total{table="a\"b"} 3
+gloader_writer_total_written_rows_total{table="users"} 7
+`
+	if got := b.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPrometheusExporterNamespace(t *testing.T) {
+	s := New()
+	s.RegisterGaugeCounter("reader.batchSize2X.rows", "").Set(1)
+
+	var b strings.Builder
+	if err := NewPrometheusExporter(s.Registry()).WithNamespace("").Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "reader_batch_size2_x_rows 1\n"; !strings.HasSuffix(b.String(), want) {
+		t.Errorf("Write() =\n%s\nwant suffix %q", b.String(), want)
+	}
+}
+
+func TestPrometheusExporterServeHTTP(t *testing.T) {
+	s := New()
+	s.RegisterSequentialCounter("rows", "").IncBy(2, "users")
+
+	rec := httptest.NewRecorder()
+	NewPrometheusExporter(s.Registry()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the Prometheus text format", got)
+	}
+	if want := `gloader_rows_total{tag="users"} 2`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("body =\n%s\nwant it to contain %q", rec.Body.String(), want)
+	}
+}
@@ -1,32 +1,45 @@
 package stats
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
 
 type Registry interface {
 	RegisterMetric(name, description string, metric Metric)
 	GetMetric(name string) (Metric, error)
 	MustGetMetric(name string) Metric
 	Description(name string) (string, error)
+	// Names returns the names of all registered metrics in ascending order.
+	Names() []string
 }
 
+// DefaultRegistry is a Registry that is safe for concurrent use.
 type DefaultRegistry struct {
 	metrics        map[string]Metric
 	descriptionMap map[string]string
+	mu             *sync.RWMutex
 }
 
 func NewDefaultRegistry() *DefaultRegistry {
 	return &DefaultRegistry{
 		metrics:        make(map[string]Metric),
 		descriptionMap: make(map[string]string),
+		mu:             &sync.RWMutex{},
 	}
 }
 
 func (r *DefaultRegistry) RegisterMetric(name, description string, metric Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.metrics[name] = metric
 	r.descriptionMap[name] = description
 }
 
 func (r *DefaultRegistry) GetMetric(name string) (Metric, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	metric, isExists := r.metrics[name]
 	if !isExists {
 		return nil, fmt.Errorf("metric %s does not exist", name)
@@ -43,9 +56,22 @@ func (r *DefaultRegistry) MustGetMetric(name string) Metric {
 }
 
 func (r *DefaultRegistry) Description(name string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	description, isExists := r.descriptionMap[name]
 	if !isExists {
 		return "", fmt.Errorf("metric %s does not exist", name)
 	}
 	return description, nil
 }
+
+func (r *DefaultRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
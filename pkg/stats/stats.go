@@ -36,6 +36,14 @@ func (s *Stats) RegisterGaugeCounter(name, description string) GaugeCounter {
 	return m
 }
 
+// RegisterHistogram registers a new histogram with the given bucket upper bounds.
+// If no bucket is given, the DefaultLatencyBuckets are used.
+func (s *Stats) RegisterHistogram(name, description string, buckets ...float64) Histogram {
+	m := NewDefaultHistogram(buckets...)
+	s.registry.RegisterMetric(name, description, m)
+	return m
+}
+
 func (s *Stats) GetSequentialCounter(name string) (SequentialCounter, error) {
 	m, err := s.registry.GetMetric(name)
 	if err != nil {
@@ -83,3 +91,23 @@ func (s *Stats) MustGetGaugeCounter(name string) GaugeCounter {
 	}
 	panic(fmt.Errorf("stats: metric %s is not a GaugeCounter", name))
 }
+
+func (s *Stats) GetHistogram(name string) (Histogram, error) {
+	m, err := s.registry.GetMetric(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := m.(Histogram); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("stats: metric %s is not a Histogram", name)
+}
+
+func (s *Stats) MustGetHistogram(name string) Histogram {
+	m, err := s.GetHistogram(name)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"sync"
+)
+
+// tagMap is a concurrency-safe map of the tagged metrics of a metric.
+// Lookups of the existing tags are lock-free, so the workers can update their tags concurrently.
+type tagMap[M any] struct {
+	metrics   sync.Map
+	newMetric func() M
+}
+
+func newTagMap[M any](newMetric func() M) *tagMap[M] {
+	return &tagMap[M]{newMetric: newMetric}
+}
+
+// get returns the metric of the given tag, and creates it if it doesn't exist.
+func (t *tagMap[M]) get(tag string) M {
+	if m, ok := t.metrics.Load(tag); ok {
+		return m.(M)
+	}
+	m, _ := t.metrics.LoadOrStore(tag, t.newMetric())
+	return m.(M)
+}
+
+// keys returns the tags of the map.
+func (t *tagMap[M]) keys() []string {
+	tags := make([]string, 0)
+	t.metrics.Range(func(key, _ any) bool {
+		tags = append(tags, key.(string))
+		return true
+	})
+	return tags
+}
+
+// subscribers is a concurrency-safe set of channels that are notified when a metric changes.
+type subscribers struct {
+	chs map[chan<- any]struct{}
+	mu  *sync.RWMutex
+}
+
+func newSubscribers() *subscribers {
+	return &subscribers{
+		chs: make(map[chan<- any]struct{}),
+		mu:  &sync.RWMutex{},
+	}
+}
+
+func (s *subscribers) add(ch chan<- any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chs[ch] = struct{}{}
+}
+
+func (s *subscribers) remove(ch chan<- any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.chs, ch)
+}
+
+// notify notifies the subscribers without blocking, a subscriber that isn't ready misses the notification.
+func (s *subscribers) notify() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for ch := range s.chs {
+		select {
+		case ch <- "notify":
+		default:
+		}
+	}
+}
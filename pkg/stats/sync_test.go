@@ -0,0 +1,177 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// The tests of this file are meant to be run with -race.
+
+func TestTagMapCreatesEachTagOnce(t *testing.T) {
+	c := NewDefaultSequentialCounter()
+	const workers, incs = 16, 1000
+
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < incs; i++ {
+				// every worker creates the same tags lazily at the same time.
+				c.Inc(fmt.Sprintf("table%d", i%10))
+			}
+		}()
+	}
+	wg.Wait()
+
+	tags := c.Tags()
+	sort.Strings(tags)
+	if len(tags) != 10 {
+		t.Fatalf("Tags() = %v, want 10 tags", tags)
+	}
+	var total int64
+	for _, tag := range tags {
+		total += c.Value(tag)
+	}
+	if total != workers*incs {
+		t.Errorf("total of the tags = %d, want %d", total, workers*incs)
+	}
+}
+
+func TestCountersConcurrentUpdates(t *testing.T) {
+	sc := NewDefaultSequentialCounter()
+	gc := NewDefaultGaugeCounter()
+	h := NewDefaultHistogram(1)
+	const workers, incs = 8, 1000
+
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < incs; i++ {
+				sc.IncBy(2)
+				sc.Inc("users")
+				gc.Inc("users")
+				gc.Dec("users")
+				gc.Set(int64(w), "last")
+				gc.IncBy(3)
+				h.Observe(0.5, "users")
+				_ = sc.Value()
+				_ = gc.Value("last")
+				_ = h.BucketCounts("users")
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if got := sc.Value(); got != 2*workers*incs {
+		t.Errorf("sequential counter = %d, want %d", got, 2*workers*incs)
+	}
+	if got := sc.Value("users"); got != workers*incs {
+		t.Errorf("sequential counter of users = %d, want %d", got, workers*incs)
+	}
+	if got := gc.Value("users"); got != 0 {
+		t.Errorf("gauge counter of users = %d, want 0", got)
+	}
+	if got := gc.Value(); got != 3*workers*incs {
+		t.Errorf("gauge counter = %d, want %d", got, 3*workers*incs)
+	}
+	if got := gc.Value("last"); got < 0 || got >= workers {
+		t.Errorf("gauge counter of last = %d, want one of the set values", got)
+	}
+	if got := h.Value("users"); got != workers*incs {
+		t.Errorf("histogram count of users = %d, want %d", got, workers*incs)
+	}
+}
+
+func TestSubscribeWhilePublishing(t *testing.T) {
+	c := NewDefaultGaugeCounter()
+	stop := make(chan struct{})
+	published := make(chan struct{})
+
+	go func() {
+		defer close(published)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				c.Inc("users")
+				c.Inc()
+			}
+		}
+	}()
+
+	wg := &sync.WaitGroup{}
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				ch := make(chan any, 1)
+				c.NotifyOnChange(ch, "users")
+				c.NotifyOnChange(ch)
+				c.Unsubscribe(ch, "users")
+				c.Unsubscribe(ch)
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-published
+
+	// a subscribed channel is notified of the next change, and an unsubscribed one isn't.
+	subscribed, unsubscribed := make(chan any, 1), make(chan any, 1)
+	c.NotifyOnChange(subscribed, "users")
+	c.NotifyOnChange(unsubscribed, "users")
+	c.Unsubscribe(unsubscribed, "users")
+	c.Inc("users")
+	select {
+	case <-subscribed:
+	default:
+		t.Error("the subscribed channel wasn't notified")
+	}
+	select {
+	case <-unsubscribed:
+		t.Error("the unsubscribed channel was notified")
+	default:
+	}
+}
+
+func TestRegistryConcurrentLookups(t *testing.T) {
+	s := New()
+	wg := &sync.WaitGroup{}
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				name := fmt.Sprintf("metric%d.%d", w, i)
+				s.RegisterSequentialCounter(name, "description of "+name)
+				s.MustGetSequentialCounter(name).Inc()
+				if _, err := s.GetGaugeCounter(name); err == nil {
+					t.Errorf("GetGaugeCounter(%s) of a sequential counter didn't fail", name)
+				}
+				if d, err := s.Registry().Description(name); err != nil || d != "description of "+name {
+					t.Errorf("Description(%s) = %q, %v", name, d, err)
+				}
+				_ = s.Registry().Names()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	names := s.Registry().Names()
+	if len(names) != 800 {
+		t.Fatalf("got %d names, want 800", len(names))
+	}
+	if !sort.StringsAreSorted(names) {
+		t.Error("Names() aren't sorted")
+	}
+	if _, err := s.Registry().GetMetric("missing"); err == nil {
+		t.Error("GetMetric(missing) didn't fail")
+	}
+}
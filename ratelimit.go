@@ -0,0 +1,84 @@
+package gloader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mohammadv184/gloader/pkg/ratelimit"
+)
+
+var ErrUnknownRateLimitKind = errors.New("unknown rate limit kind")
+
+// RateLimitKind is the kind of throughput that is limited.
+type RateLimitKind uint8
+
+const (
+	// RateLimitReadRows limits the rows read from the source per second.
+	RateLimitReadRows RateLimitKind = iota
+	// RateLimitReadBytes limits the bytes read from the source per second.
+	RateLimitReadBytes
+	// RateLimitWriteRows limits the rows written to the destination per second.
+	RateLimitWriteRows
+)
+
+var rateLimitKindNames = map[RateLimitKind]string{
+	RateLimitReadRows:  "read-rows",
+	RateLimitReadBytes: "read-bytes",
+	RateLimitWriteRows: "write-rows",
+}
+
+// String returns the name of the rate limit kind.
+func (k RateLimitKind) String() string {
+	return rateLimitKindNames[k]
+}
+
+// GetRateLimitKindFromString returns the rate limit kind from its name.
+func GetRateLimitKindFromString(kind string) (RateLimitKind, error) {
+	for k, v := range rateLimitKindNames {
+		if strings.EqualFold(v, kind) {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownRateLimitKind, kind)
+}
+
+// rateLimitKey identifies a rate limiter. An empty data collection is the global rate limiter.
+type rateLimitKey struct {
+	dataCollection string
+	kind           RateLimitKind
+}
+
+// rateLimiters holds the global and per data collection rate limiters shared by the readers and writers.
+// The limiters are created on demand and never removed, so their rates can be changed while they are used.
+type rateLimiters struct {
+	limiters map[rateLimitKey]*ratelimit.Limiter
+	mu       *sync.Mutex
+}
+
+func newRateLimiters() *rateLimiters {
+	return &rateLimiters{
+		limiters: make(map[rateLimitKey]*ratelimit.Limiter),
+		mu:       &sync.Mutex{},
+	}
+}
+
+// get returns the limiter of the given data collection and kind.
+func (rl *rateLimiters) get(dataCollection string, kind RateLimitKind) *ratelimit.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	key := rateLimitKey{dataCollection: dataCollection, kind: kind}
+	l, ok := rl.limiters[key]
+	if !ok {
+		l = ratelimit.New(0)
+		rl.limiters[key] = l
+	}
+	return l
+}
+
+// of returns the global and the data collection limiters of the given kind.
+func (rl *rateLimiters) of(dataCollection string, kind RateLimitKind) ratelimit.Limiters {
+	return ratelimit.Limiters{rl.get("", kind), rl.get(dataCollection, kind)}
+}
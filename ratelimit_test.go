@@ -0,0 +1,46 @@
+package gloader
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetRateLimitKindFromString(t *testing.T) {
+	for kind, name := range rateLimitKindNames {
+		got, err := GetRateLimitKindFromString(name)
+		if err != nil || got != kind {
+			t.Errorf("GetRateLimitKindFromString(%q) = %v, %v, want %v", name, got, err, kind)
+		}
+	}
+	if _, err := GetRateLimitKindFromString("write-bytes"); !errors.Is(err, ErrUnknownRateLimitKind) {
+		t.Errorf("GetRateLimitKindFromString(write-bytes) error = %v, want %v", err, ErrUnknownRateLimitKind)
+	}
+}
+
+func TestRateLimitsAreSharedAndAdjustable(t *testing.T) {
+	g := NewGLoader()
+	// the limiters are handed to the workers before they're limited, and they're adjusted in place.
+	limiters := g.rateLimiters.of("users", RateLimitReadRows)
+
+	g.SetRateLimit(RateLimitReadRows, 1000)
+	g.SetDataCollectionRateLimit("users", RateLimitReadRows, 100)
+
+	if got := limiters[0].Rate(); got != 1000 {
+		t.Errorf("global limiter rate = %v, want 1000", got)
+	}
+	if got := limiters[1].Rate(); got != 100 {
+		t.Errorf("data collection limiter rate = %v, want 100", got)
+	}
+	if got := g.GetRateLimit("", RateLimitReadRows); got != 1000 {
+		t.Errorf("GetRateLimit(global) = %v, want 1000", got)
+	}
+	if got := g.GetRateLimit("users", RateLimitReadRows); got != 100 {
+		t.Errorf("GetRateLimit(users) = %v, want 100", got)
+	}
+	if got := g.GetRateLimit("orders", RateLimitReadRows); got != 0 {
+		t.Errorf("GetRateLimit(orders) = %v, want 0", got)
+	}
+	if got := g.GetRateLimit("users", RateLimitWriteRows); got != 0 {
+		t.Errorf("GetRateLimit(users, write-rows) = %v, want 0", got)
+	}
+}
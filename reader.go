@@ -3,14 +3,34 @@ package gloader
 import (
 	"context"
 	"errors"
-	"fmt"
-	"log"
 	"sync"
+	"time"
 
 	"github.com/mohammadv184/gloader/data"
 	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/log"
+	"github.com/mohammadv184/gloader/pkg/ratelimit"
 )
 
+const (
+	// DefaultReadRetryBackoff is the wait before the first retry of a failed read,
+	// it's doubled on each following retry of the same batch up to DefaultMaxReadRetryBackoff.
+	DefaultReadRetryBackoff    = 100 * time.Millisecond
+	DefaultMaxReadRetryBackoff = 10 * time.Second
+)
+
+// ReaderObserver is an interface that can be implemented to observe the reader workers.
+type ReaderObserver interface {
+	// BatchRead is called when a batch is read from the source.
+	BatchRead(rows, bytes uint64, latency time.Duration)
+	// ReadFailed is called when reading a batch fails.
+	ReadFailed(err error)
+	// ReadRetried is called when a failed read is retried.
+	ReadRetried()
+	// Reconnected is called when a closed connection is replaced with a new one.
+	Reconnected()
+}
+
 type Reader struct {
 	connectionP    *driver.ConnectionPool
 	buffer         *data.Buffer
@@ -20,6 +40,14 @@ type Reader struct {
 	workers        uint
 	startOffset    uint64
 	endOffset      uint64
+	batchSize      *AdaptiveBatchSize
+	rowsLimiters   ratelimit.Limiters
+	bytesLimiters  ratelimit.Limiters
+	observer       ReaderObserver
+	columnMapping  map[string]string
+	retryBackoff   time.Duration
+	maxBackoff     time.Duration
+	logger         *log.Logger
 	ctx            context.Context
 }
 
@@ -31,6 +59,9 @@ func NewReader(ctx context.Context, dataCollection string, buffer *data.Buffer,
 		dataMap:        dataMap,
 		rowPerBatch:    DefaultRowsPerBatch,
 		workers:        DefaultWorkers,
+		retryBackoff:   DefaultReadRetryBackoff,
+		maxBackoff:     DefaultMaxReadRetryBackoff,
+		logger:         log.Default(),
 		ctx:            ctx,
 	}
 }
@@ -39,6 +70,44 @@ func (r *Reader) SetRowsPerBatch(rowsPerBatch uint64) {
 	r.rowPerBatch = rowsPerBatch
 }
 
+// SetAdaptiveBatchSize makes the workers tune the number of rows per batch at runtime,
+// instead of using the static rows per batch.
+func (r *Reader) SetAdaptiveBatchSize(batchSize *AdaptiveBatchSize) {
+	r.batchSize = batchSize
+}
+
+// SetRowsRateLimiters sets the rate limiters of the rows read per second.
+func (r *Reader) SetRowsRateLimiters(limiters ...*ratelimit.Limiter) {
+	r.rowsLimiters = limiters
+}
+
+// SetBytesRateLimiters sets the rate limiters of the bytes read per second.
+func (r *Reader) SetBytesRateLimiters(limiters ...*ratelimit.Limiter) {
+	r.bytesLimiters = limiters
+}
+
+// SetObserver sets the observer of the reader workers.
+func (r *Reader) SetObserver(observer ReaderObserver) {
+	r.observer = observer
+}
+
+// SetColumnMapping renames the columns of the read data sets with the given mapping.
+func (r *Reader) SetColumnMapping(mapping map[string]string) {
+	r.columnMapping = mapping
+}
+
+// SetRetryBackoff sets the wait before the first retry of a failed read, and the maximum wait
+// it's doubled up to on the following retries of the same batch.
+func (r *Reader) SetRetryBackoff(backoff, maxBackoff time.Duration) {
+	r.retryBackoff = backoff
+	r.maxBackoff = maxBackoff
+}
+
+// SetLogger sets the logger of the reader workers.
+func (r *Reader) SetLogger(logger *log.Logger) {
+	r.logger = logger
+}
+
 func (r *Reader) SetWorkers(workers uint) {
 	r.workers = workers
 }
@@ -69,80 +138,168 @@ func (r *Reader) Start() error {
 	}
 
 	defer func() {
-		fmt.Println("closing buffer")
+		r.logger.Debug("closing buffer")
+		// the buffer is already closed when the context of the reader is canceled.
 		err := r.buffer.Close()
-		if err != nil {
-			// TODO: logging system
-			//log.Println(err)
+		if err != nil && !errors.Is(err, data.ErrBufferAlreadyIsClosed) {
+			r.logger.Warn("failed to close the buffer", log.Err(err))
 		}
 	}()
 
 	wg := &sync.WaitGroup{}
 	wg.Add(int(r.workers))
 
+	errs := make([]error, r.workers)
 	for i := uint(0); i < r.workers; i++ {
 		startOffset := r.startOffset + uint64(float64(i)*float64(r.endOffset-r.startOffset)/float64(r.workers))
 
 		endOffset := r.startOffset + uint64(float64(i+1)*float64(r.endOffset-r.startOffset)/float64(r.workers))
-		fmt.Println(startOffset, endOffset)
+		r.logger.Debug("starting reader worker", "start_offset", startOffset, "end_offset", endOffset)
 
-		go func(startOffset, endOffset uint64) {
+		go func(i uint, startOffset, endOffset uint64) {
 			defer wg.Done()
-			r.RunWorker(startOffset, endOffset, r.rowPerBatch)
-		}(startOffset, endOffset)
+			errs[i] = r.RunWorker(startOffset, endOffset, r.rowPerBatch)
+		}(i, startOffset, endOffset)
 	}
 
 	wg.Wait()
-	return nil
+	return errors.Join(errs...)
 }
 
-func (r *Reader) RunWorker(startOffset, endOffset, rowPerBatch uint64) {
+// RunWorker reads the data sets between the given offsets into the buffer.
+// It returns the first error that stops the worker, unless the reader is stopped.
+func (r *Reader) RunWorker(startOffset, endOffset, rowPerBatch uint64) error {
 	wg := &sync.WaitGroup{}
 	wg.Add(2)
 
+	ctx, span := startSpan(
+		r.ctx,
+		"Reader.RunWorker",
+		driver.AttributeDataCollection.String(r.dataCollection),
+		driver.AttributeStartOffset.Int64(int64(startOffset)),
+		driver.AttributeEndOffset.Int64(int64(endOffset)),
+	)
+	defer span.End()
+
 	ch := make(chan *data.Batch)
+	// stop is closed when the batches can't be written to the buffer anymore, to stop reading them.
+	stop := make(chan struct{})
 	conn, cIndex, err := r.connectionP.Connect(r.ctx)
 	if err != nil {
-		panic(err)
+		if r.ctx.Err() != nil {
+			// the reader is stopped before the worker is started.
+			return nil
+		}
+		return err
 	}
 	rConn := conn.(driver.ReadableConnection)
 
 	go func() {
 		for i := startOffset; i < endOffset; i += rowPerBatch {
+			if r.batchSize != nil {
+				rowPerBatch = r.batchSize.Current()
+			}
 			if i+rowPerBatch > endOffset {
 				rowPerBatch = endOffset - i
 				if rowPerBatch == 0 {
 					break
 				}
 			}
+			readCtx, readSpan := startSpan(
+				ctx,
+				"Reader.read",
+				driver.AttributeDataCollection.String(r.dataCollection),
+				driver.AttributeStartOffset.Int64(int64(i)),
+			)
+			var retries int64
 		retryRead:
-			batch, err := rConn.Read(r.ctx, r.dataCollection, i, i+rowPerBatch)
+			readStart := time.Now()
+			batch, err := rConn.Read(readCtx, r.dataCollection, i, i+rowPerBatch)
 			if err != nil {
 				select {
 				case <-r.ctx.Done():
+					driver.EndSpan(readSpan, err)
 					goto stopWorker
 				default:
 				}
+				if r.observer != nil {
+					r.observer.ReadFailed(err)
+				}
+				readSpan.RecordError(err)
+				retries++
+				readSpan.SetAttributes(driver.AttributeRetryCount.Int64(retries))
+				if r.batchSize != nil {
+					r.batchSize.Backoff()
+					if current := r.batchSize.Current(); current < rowPerBatch {
+						rowPerBatch = current
+					}
+				}
 				if errors.Is(err, driver.ErrConnectionIsClosed) {
 					conn, cIndex, err = r.connectionP.Connect(r.ctx)
 					if err != nil {
-						log.Printf("error on connecting to database err: %s\n", err)
+						r.logger.Error("failed to connect to the source", log.Err(err))
+						if r.observer != nil {
+							r.observer.ReadRetried()
+						}
+						if !r.waitRetry(retries) {
+							driver.EndSpan(readSpan, err)
+							goto stopWorker
+						}
 						goto retryRead
 					}
 					rConn = conn.(driver.ReadableConnection)
+					if r.observer != nil {
+						r.observer.Reconnected()
+					}
+				}
+				r.logger.Warn("failed to read data batch, retrying",
+					"start_offset", i,
+					"end_offset", i+rowPerBatch,
+					log.Err(err),
+				)
+				if r.observer != nil {
+					r.observer.ReadRetried()
+				}
+				if !r.waitRetry(retries) {
+					driver.EndSpan(readSpan, err)
+					goto stopWorker
 				}
-				log.Printf("error on reading data batch from %d,%d err: %s\n", i, i+rowPerBatch, err)
 				goto retryRead
 
 			}
+			readLatency := time.Since(readStart)
+			readSpan.SetAttributes(
+				driver.AttributeEndOffset.Int64(int64(i+rowPerBatch)),
+				driver.AttributeBatchRows.Int64(int64(batch.GetLength())),
+				driver.AttributeBatchBytes.Int64(int64(batch.GetSize())),
+				driver.AttributeRetryCount.Int64(retries),
+			)
+			readSpan.End()
+			if r.batchSize != nil {
+				r.batchSize.Observe(batch.GetLength(), batch.GetSize(), readLatency)
+			}
+			if r.observer != nil {
+				r.observer.BatchRead(batch.GetLength(), batch.GetSize(), readLatency)
+			}
 			if batch.GetLength() == 0 {
 				continue
 			}
+			if len(r.columnMapping) > 0 {
+				renameColumns(batch, r.columnMapping)
+			}
+
+			if err := r.rowsLimiters.WaitN(r.ctx, batch.GetLength()); err != nil {
+				goto stopWorker
+			}
+			if err := r.bytesLimiters.WaitN(r.ctx, batch.GetSize()); err != nil {
+				goto stopWorker
+			}
 
 			select {
 			case <-r.ctx.Done():
-				// TODO: logging system
-				//log.Printf("context canceled: raeder worker %s:%d,%d stoped\n", r.dataCollection, startOffset, endOffset)
+				r.logger.Debug("context canceled, stopping reader worker", "start_offset", startOffset, "end_offset", endOffset)
+				goto stopWorker
+			case <-stop:
 				goto stopWorker
 			case ch <- batch:
 				continue
@@ -152,25 +309,37 @@ func (r *Reader) RunWorker(startOffset, endOffset, rowPerBatch uint64) {
 	stopWorker:
 		err := r.connectionP.CloseConnection(cIndex)
 		if err != nil {
-			// TODO: logging system
-			//log.Println("failed on closing database connection err:", err)
+			r.logger.Warn("failed to close the source connection", log.Err(err))
 		}
 		wg.Done()
 		close(ch)
 	}()
 
+	var writeErr error
 	go func() {
+		defer wg.Done()
 		for {
 			select {
 			case batch, ok := <-ch:
 				if !ok {
-					//log.Printf("batch readCh closed %s:%d,%d\n", r.dataCollection, startOffset, endOffset)
-					wg.Done()
 					return
 				}
-				err := r.buffer.Write(*batch...)
+				_, writeSpan := startSpan(
+					ctx,
+					"Buffer.WriteBatch",
+					driver.AttributeDataCollection.String(r.dataCollection),
+					driver.AttributeBatchRows.Int64(int64(batch.GetLength())),
+				)
+				err := r.buffer.WriteBatch(batch)
+				driver.EndSpan(writeSpan, err)
 				if err != nil {
-					panic(err)
+					if errors.Is(err, data.ErrBufferIsClosed) && r.ctx.Err() != nil {
+						// the buffer is closed because the reader is stopped, so the batch is dropped.
+						continue
+					}
+					r.logger.Error("failed to write data batch to the buffer", log.Err(err))
+					writeErr = err
+					close(stop)
 					return
 				}
 			}
@@ -178,4 +347,36 @@ func (r *Reader) RunWorker(startOffset, endOffset, rowPerBatch uint64) {
 	}()
 
 	wg.Wait()
+	return writeErr
+}
+
+// waitRetry waits before the given retry of a failed read, and returns false if the reader is stopped meanwhile.
+func (r *Reader) waitRetry(retry int64) bool {
+	backoff := r.retryBackoff
+	for i := int64(1); i < retry && backoff < r.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.maxBackoff {
+		backoff = r.maxBackoff
+	}
+
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+	select {
+	case <-r.ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// renameColumns renames the columns of the data sets of the batch with the given mapping.
+func renameColumns(batch *data.Batch, mapping map[string]string) {
+	for _, set := range *batch {
+		for _, d := range *set {
+			if name, ok := mapping[d.GetKey()]; ok {
+				d.SetKey(name)
+			}
+		}
+	}
 }
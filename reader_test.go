@@ -0,0 +1,152 @@
+package gloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/driver"
+)
+
+func newTestReader(t *testing.T, ctx context.Context, src *memoryDatabase, buffer *data.Buffer) *Reader {
+	t.Helper()
+	d, err := driver.GetDriver("memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, err := (&memoryConnection{db: src}).GetDetails(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc, err := details.GetDataCollection("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := NewReader(ctx, "users", buffer, dc.DataMap, driver.NewConnectionPool(driver.NewConnector(d, src.name)))
+	reader.SetWorkers(1)
+	reader.SetEndOffset(uint64(dc.DataSetCount))
+	return reader
+}
+
+func TestReaderBacksOffWhileReconnecting(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 10)
+
+	var (
+		mu       sync.Mutex
+		attempts []time.Time
+		opens    int
+	)
+	src.readErr = func(string, uint64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, time.Now())
+		if len(attempts) <= 3 {
+			return driver.ErrConnectionIsClosed
+		}
+		return nil
+	}
+	src.openErr = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		opens++
+		// the first connection is opened, then the first two reconnections fail.
+		if opens == 2 || opens == 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	buffer := data.NewBuffer(context.Background())
+	reader := newTestReader(t, context.Background(), src, buffer)
+	reader.SetRetryBackoff(20*time.Millisecond, 40*time.Millisecond)
+	if err := reader.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 4 {
+		t.Fatalf("read attempts = %d, want 4", len(attempts))
+	}
+	// the retries wait 20ms, then 40ms, then 40ms at most.
+	for i, want := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond} {
+		if got := attempts[i+1].Sub(attempts[i]); got < want {
+			t.Errorf("wait before retry %d = %v, want at least %v", i+1, got, want)
+		}
+	}
+	if got := buffer.GetLength(); got != 10 {
+		t.Errorf("buffered data sets = %d, want 10", got)
+	}
+}
+
+func TestReaderStopsWhileBackingOff(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 10)
+	src.readErr = func(string, uint64) error {
+		return errors.New("read failed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	buffer := data.NewBuffer(ctx)
+	reader := newTestReader(t, ctx, src, buffer)
+	reader.SetRetryBackoff(time.Hour, time.Hour)
+
+	done := make(chan error)
+	go func() { done <- reader.Start() }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the reader didn't stop while it was backing off")
+	}
+}
+
+func TestReaderReturnsErrors(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	tests := []struct {
+		name    string
+		prepare func(src *memoryDatabase, buffer *data.Buffer)
+		want    error
+	}{
+		{
+			name: "connection",
+			prepare: func(src *memoryDatabase, _ *data.Buffer) {
+				src.openErr = func() error { return errRefused }
+			},
+			want: errRefused,
+		},
+		{
+			name: "buffer",
+			prepare: func(_ *memoryDatabase, buffer *data.Buffer) {
+				// the buffer is closed while the reader isn't stopped.
+				_ = buffer.Close()
+			},
+			want: data.ErrBufferIsClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newMemoryDatabase(t, "src")
+			src.addDataCollection(t, "users", 10)
+			buffer := data.NewBuffer(context.Background())
+			reader := newTestReader(t, context.Background(), src, buffer)
+			reader.SetWorkers(2)
+			reader.SetRowsPerBatch(1)
+			tt.prepare(src, buffer)
+
+			if err := reader.Start(); !errors.Is(err, tt.want) {
+				t.Errorf("Start() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package gloader
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/mohammadv184/gloader/driver"
+)
+
+// Scheduler limits the number of data collections that are loaded at the same time,
+// and the total number of connections they hold.
+// A zero limit means unlimited.
+type Scheduler struct {
+	maxDataCollections uint
+	maxConnections     uint
+	dataCollections    uint
+	connections        uint
+	mu                 *sync.Mutex
+	cond               *sync.Cond
+}
+
+// NewScheduler returns a new scheduler with the given limits.
+func NewScheduler(maxDataCollections, maxConnections uint) *Scheduler {
+	mu := &sync.Mutex{}
+	return &Scheduler{
+		maxDataCollections: maxDataCollections,
+		maxConnections:     maxConnections,
+		mu:                 mu,
+		cond:               sync.NewCond(mu),
+	}
+}
+
+// Acquire blocks until a data collection that holds the given number of connections can be started.
+// If the number of connections exceeds the connections budget, the whole budget is acquired instead,
+// so the data collection runs alone.
+// It returns the number of acquired connections, which must be passed to Release.
+// If the context is done before that, or it's already done, the context error is returned.
+func (s *Scheduler) Acquire(ctx context.Context, connections uint) (uint, error) {
+	if s.maxConnections > 0 && connections > s.maxConnections {
+		connections = s.maxConnections
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.cond.Broadcast()
+	})
+	defer stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		// a canceled run mustn't start data collections, even if it has room for them.
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if s.canStart(connections) {
+			break
+		}
+		s.cond.Wait()
+	}
+
+	s.dataCollections++
+	s.connections += connections
+	return connections, nil
+}
+
+// Release releases a data collection that was started with Acquire.
+func (s *Scheduler) Release(connections uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataCollections--
+	s.connections -= connections
+	s.cond.Broadcast()
+}
+
+func (s *Scheduler) canStart(connections uint) bool {
+	if s.maxDataCollections > 0 && s.dataCollections >= s.maxDataCollections {
+		return false
+	}
+	if s.maxConnections > 0 && s.connections+connections > s.maxConnections {
+		return false
+	}
+	return true
+}
+
+// sortDataCollectionsBySize sorts the data collections by their data set count in descending order.
+// Loading the largest data collections first minimizes the total wall time.
+func sortDataCollectionsBySize(dcs []driver.DataCollectionDetail) {
+	sort.SliceStable(dcs, func(i, j int) bool {
+		return dcs[i].DataSetCount > dcs[j].DataSetCount
+	})
+}
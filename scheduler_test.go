@@ -0,0 +1,140 @@
+package gloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mohammadv184/gloader/driver"
+)
+
+func TestSchedulerLimitsDataCollections(t *testing.T) {
+	s := NewScheduler(2, 0)
+
+	var running, maxRunning int64
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			connections, err := s.Acquire(context.Background(), 3)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := atomic.AddInt64(&running, 1)
+			for {
+				m := atomic.LoadInt64(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt64(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&running, -1)
+			s.Release(connections)
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning > 2 {
+		t.Errorf("%d data collections ran at the same time, want at most 2", maxRunning)
+	}
+}
+
+func TestSchedulerLimitsConnections(t *testing.T) {
+	s := NewScheduler(0, 5)
+
+	first, err := s.Acquire(context.Background(), 3)
+	if err != nil || first != 3 {
+		t.Fatalf("Acquire(3) = %d, %v, want 3", first, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire over the budget error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	acquired := make(chan uint)
+	go func() {
+		connections, err := s.Acquire(context.Background(), 3)
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- connections
+	}()
+	s.Release(first)
+
+	select {
+	case connections := <-acquired:
+		if connections != 3 {
+			t.Errorf("Acquire(3) = %d, want 3", connections)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire wasn't unblocked by Release")
+	}
+}
+
+func TestSchedulerClampsToBudget(t *testing.T) {
+	s := NewScheduler(0, 4)
+
+	connections, err := s.Acquire(context.Background(), 10)
+	if err != nil || connections != 4 {
+		t.Fatalf("Acquire(10) = %d, %v, want the whole budget of 4", connections, err)
+	}
+	s.Release(connections)
+}
+
+func TestSchedulerAcquireCanceled(t *testing.T) {
+	s := NewScheduler(1, 0)
+	if _, err := s.Acquire(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := s.Acquire(ctx, 1)
+		done <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Acquire error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire wasn't unblocked by the canceled context")
+	}
+}
+
+func TestSchedulerAcquireAfterCancel(t *testing.T) {
+	s := NewScheduler(0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Acquire(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSortDataCollectionsBySize(t *testing.T) {
+	dcs := []driver.DataCollectionDetail{
+		{Name: "small", DataSetCount: 10},
+		{Name: "large", DataSetCount: 1000},
+		{Name: "empty"},
+		{Name: "medium", DataSetCount: 100},
+		{Name: "small2", DataSetCount: 10},
+	}
+	sortDataCollectionsBySize(dcs)
+
+	want := []string{"large", "medium", "small", "small2", "empty"}
+	for i, dc := range dcs {
+		if dc.Name != want[i] {
+			t.Fatalf("sortDataCollectionsBySize() = %v, want %v", dcs, want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package gloader
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mohammadv184/gloader/driver"
+)
+
+var ErrInvalidDataCollectionPattern = errors.New("invalid data collection pattern")
+
+// DataCollectionPattern matches the names of data collections.
+// It's an exact name, a glob like orders_* (see path.Match), or a regular expression between slashes like /^tmp_/.
+type DataCollectionPattern struct {
+	pattern string
+	glob    bool
+	regex   *regexp.Regexp
+}
+
+// ParseDataCollectionPattern parses the given exact name, glob or regular expression.
+func ParseDataCollectionPattern(pattern string) (DataCollectionPattern, error) {
+	p := DataCollectionPattern{pattern: pattern}
+	switch {
+	case len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/"):
+		regex, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return p, fmt.Errorf("%w %q: %v", ErrInvalidDataCollectionPattern, pattern, err)
+		}
+		p.regex = regex
+	case strings.ContainsAny(pattern, "*?["):
+		if _, err := path.Match(pattern, ""); err != nil {
+			return p, fmt.Errorf("%w %q: %v", ErrInvalidDataCollectionPattern, pattern, err)
+		}
+		p.glob = true
+	}
+	return p, nil
+}
+
+// Match reports whether the name of the data collection matches the pattern.
+func (p DataCollectionPattern) Match(name string) bool {
+	switch {
+	case p.regex != nil:
+		return p.regex.MatchString(name)
+	case p.glob:
+		ok, _ := path.Match(p.pattern, name)
+		return ok
+	default:
+		return p.pattern == name
+	}
+}
+
+// String returns the pattern as it was given.
+func (p DataCollectionPattern) String() string {
+	return p.pattern
+}
+
+// SelectDataCollections returns the data collections which match any of the include patterns (all of them
+// if there's none), and don't match any of the exclude patterns, in their original order.
+// The patterns which matched no data collection are returned too.
+func SelectDataCollections(dcs []driver.DataCollectionDetail, include, exclude []string) ([]driver.DataCollectionDetail, []string, error) {
+	includePatterns, err := parseDataCollectionPatterns(include)
+	if err != nil {
+		return nil, nil, err
+	}
+	excludePatterns, err := parseDataCollectionPatterns(exclude)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matched := make(map[string]bool)
+	matchAny := func(patterns []DataCollectionPattern, name string) bool {
+		var ok bool
+		for _, p := range patterns {
+			if p.Match(name) {
+				// all the patterns are checked, so each one is marked as matched.
+				matched[p.String()] = true
+				ok = true
+			}
+		}
+		return ok
+	}
+
+	var selected []driver.DataCollectionDetail
+	for _, dc := range dcs {
+		included := len(includePatterns) == 0 || matchAny(includePatterns, dc.Name)
+		excluded := matchAny(excludePatterns, dc.Name)
+		if included && !excluded {
+			selected = append(selected, dc)
+		}
+	}
+
+	var unmatched []string
+	for _, p := range append(includePatterns, excludePatterns...) {
+		if !matched[p.String()] {
+			unmatched = append(unmatched, p.String())
+		}
+	}
+	return selected, unmatched, nil
+}
+
+func parseDataCollectionPatterns(patterns []string) ([]DataCollectionPattern, error) {
+	parsed := make([]DataCollectionPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		p, err := ParseDataCollectionPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,137 @@
+package gloader
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/log"
+)
+
+func TestDataCollectionPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{pattern: "orders", match: []string{"orders"}, noMatch: []string{"orders_2023", "order"}},
+		{pattern: "orders_*", match: []string{"orders_", "orders_2023"}, noMatch: []string{"orders", "old_orders_1"}},
+		{pattern: "log_?", match: []string{"log_1"}, noMatch: []string{"log_10"}},
+		{pattern: "[ab]*", match: []string{"accounts", "bills"}, noMatch: []string{"cards"}},
+		// a regular expression isn't anchored unless it says so.
+		{pattern: "/^tmp_/", match: []string{"tmp_users"}, noMatch: []string{"users_tmp_1"}},
+		{pattern: "/_(old|bak)$/", match: []string{"users_old", "orders_bak"}, noMatch: []string{"users_older"}},
+		{pattern: "/", match: []string{"/"}, noMatch: []string{""}},
+	}
+	for _, tt := range tests {
+		p, err := ParseDataCollectionPattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("ParseDataCollectionPattern(%q) error = %v", tt.pattern, err)
+		}
+		for _, name := range tt.match {
+			if !p.Match(name) {
+				t.Errorf("%q doesn't match %q", tt.pattern, name)
+			}
+		}
+		for _, name := range tt.noMatch {
+			if p.Match(name) {
+				t.Errorf("%q matches %q", tt.pattern, name)
+			}
+		}
+	}
+
+	for _, pattern := range []string{"/(/", "orders_[", "[a-"} {
+		if _, err := ParseDataCollectionPattern(pattern); !errors.Is(err, ErrInvalidDataCollectionPattern) {
+			t.Errorf("ParseDataCollectionPattern(%q) error = %v, want %v", pattern, err, ErrInvalidDataCollectionPattern)
+		}
+	}
+}
+
+func TestSelectDataCollections(t *testing.T) {
+	var dcs []driver.DataCollectionDetail
+	for _, name := range []string{"users", "orders_2022", "orders_2023", "tmp_orders", "sessions"} {
+		dcs = append(dcs, driver.DataCollectionDetail{Name: name})
+	}
+	names := func(dcs []driver.DataCollectionDetail) []string {
+		var names []string
+		for _, dc := range dcs {
+			names = append(names, dc.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want, unmatched  []string
+	}{
+		{name: "all", want: []string{"users", "orders_2022", "orders_2023", "tmp_orders", "sessions"}},
+		{name: "exclude", exclude: []string{"/^tmp_/", "sessions"}, want: []string{"users", "orders_2022", "orders_2023"}},
+		// the original order is kept, not the order of the patterns.
+		{name: "include", include: []string{"sessions", "orders_*"}, want: []string{"orders_2022", "orders_2023", "sessions"}},
+		{
+			name:    "include and exclude",
+			include: []string{"*orders*"},
+			exclude: []string{"orders_2022", "tmp_*"},
+			want:    []string{"orders_2023"},
+		},
+		{
+			name:      "unmatched",
+			include:   []string{"users", "payments_*"},
+			exclude:   []string{"/^bak_/"},
+			want:      []string{"users"},
+			unmatched: []string{"payments_*", "/^bak_/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, unmatched, err := SelectDataCollections(dcs, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(selected); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(unmatched, tt.unmatched) {
+				t.Errorf("unmatched = %v, want %v", unmatched, tt.unmatched)
+			}
+		})
+	}
+
+	if _, _, err := SelectDataCollections(dcs, nil, []string{"/(/"}); !errors.Is(err, ErrInvalidDataCollectionPattern) {
+		t.Errorf("SelectDataCollections() error = %v, want %v", err, ErrInvalidDataCollectionPattern)
+	}
+}
+
+func TestGLoaderIncludesAndExcludesDataCollections(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	dest := newMemoryDatabase(t, "dest")
+	for _, name := range []string{"orders_2022", "orders_2023", "users"} {
+		src.addDataCollection(t, name, 5)
+		dest.addDataCollection(t, name, 0)
+	}
+
+	out := &logBuffer{}
+	g := newTestGLoader(t, src, dest).SetLogger(log.NewLogger(log.NewHandler(out, out)))
+	g.Include("orders_*", "payments").Exclude("orders_2022")
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]int{"orders_2022": 0, "orders_2023": 5, "users": 0} {
+		if got := len(dest.ids(name)); got != want {
+			t.Errorf("got %d data sets in %s, want %d", got, name, want)
+		}
+	}
+	logs := out.String()
+	for _, want := range []string{
+		"WARN pattern matched no data collection pattern=payments",
+		"INFO resolved data collections count=1 data_collections=orders_2023",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("logs don't contain %q:\n%s", want, logs)
+		}
+	}
+}
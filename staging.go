@@ -0,0 +1,208 @@
+package gloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/log"
+)
+
+var (
+	ErrStagingNotSupported       = errors.New("destination does not support staging")
+	ErrStagingVerificationFailed = errors.New("staging verification failed")
+)
+
+// stagingSuffix and oldSuffix are appended to the name of a destination data collection
+// to name its staging data collection and its old version while they're swapped.
+const (
+	stagingSuffix = "__gloader_tmp"
+	oldSuffix     = "__gloader_old"
+)
+
+// SetStaging sets whether all the data collections are loaded into staging data collections,
+// which replace the destination data collections atomically when they're loaded, so the destination
+// data collections are never seen half-loaded. A failed data collection leaves its destination untouched.
+func (g *GLoader) SetStaging(staging bool) *GLoader {
+	g.staging = staging
+	return g
+}
+
+// SetDataCollectionStaging sets whether the data collection is loaded into a staging data collection,
+// instead of the global setting.
+func (g *GLoader) SetDataCollectionStaging(dataCollection string, staging bool) *GLoader {
+	g.dataCollectionStaging[dataCollection] = staging
+	return g
+}
+
+// SetStagingVerification sets whether the data sets of the staging data collections are counted
+// before they're swapped in, and the data collections whose counts don't match the data sets read
+// from the source fail.
+func (g *GLoader) SetStagingVerification(verify bool) *GLoader {
+	g.stagingVerification = verify
+	return g
+}
+
+// stagingOf returns true if the given data collection is loaded into a staging data collection.
+// The LoadSwap data collections are always staged.
+func (g *GLoader) stagingOf(dataCollection string) bool {
+	if g.loadStrategyOf(dataCollection) == LoadSwap {
+		return true
+	}
+	if staging, ok := g.dataCollectionStaging[dataCollection]; ok {
+		return staging
+	}
+	return g.staging
+}
+
+// checkStaging returns an error if the destination doesn't support staging the given data collection.
+func (g *GLoader) checkStaging(dc driver.DataCollectionDetail, destConn driver.Connection) error {
+	_, isDroppable := destConn.(driver.DroppableConnection)
+	_, isCreatable := destConn.(driver.CreatableConnection)
+	_, isRenamable := destConn.(driver.RenamableConnection)
+	ok := isDroppable && isCreatable && isRenamable
+	if g.loadStrategyOf(dc.Name) == LoadAppend {
+		// the existing data sets are copied into the staging data collection.
+		_, isCopyable := destConn.(driver.CopyableConnection)
+		ok = ok && isCopyable
+	}
+	if g.stagingVerification {
+		_, isCountable := destConn.(driver.CountableConnection)
+		ok = ok && isCountable
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s of %s", ErrStagingNotSupported, g.loadStrategyOf(dc.Name), dc.Name)
+	}
+	return nil
+}
+
+// checkStagingForeignKeys returns an error if the destination data collection of the given one has foreign keys,
+// because its staging data collection is created like it without them, so they'd be lost when it's swapped in.
+// A recreated data collection loses its foreign keys anyway.
+func (g *GLoader) checkStagingForeignKeys(dc driver.DataCollectionDetail, dDetails driver.DatabaseDetail) error {
+	if g.loadStrategyOf(dc.Name) == LoadRecreate {
+		return nil
+	}
+	dDC, err := dDetails.GetDataCollection(g.destDataCollectionOf(dc.Name))
+	if err != nil || len(dDC.ForeignKeys) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(dDC.ForeignKeys))
+	for _, fk := range dDC.ForeignKeys {
+		names = append(names, fk.Name)
+	}
+	return fmt.Errorf("%w: %s has the foreign keys %s, which aren't copied to its staging",
+		ErrStagingNotSupported, dDC.Name, strings.Join(names, ", "))
+}
+
+// prepareStaging creates the staging data collection of the destination data collection,
+// and returns its details. It has the destination schema, or the source schema if the destination is recreated,
+// and the existing data sets of the destination if they're appended to.
+func (g *GLoader) prepareStaging(ctx context.Context, destConn driver.Connection, dc, dDC driver.DataCollectionDetail) (driver.DataCollectionDetail, error) {
+	staging := dDC
+	staging.Name = dDC.Name + stagingSuffix
+	g.logger.WithPrefix(dc.Name).Info("creating the staging of the destination", "dest", dDC.Name, "staging", staging.Name)
+
+	// a staging data collection may be left by a run that was killed.
+	if err := destConn.(driver.DroppableConnection).DropDataCollection(ctx, staging.Name); err != nil {
+		return dDC, err
+	}
+
+	switch g.loadStrategyOf(dc.Name) {
+	case LoadRecreate:
+		return staging, destConn.(driver.CreatableConnection).CreateDataCollection(ctx, staging.Name, dc)
+	case LoadAppend:
+		if err := destConn.(driver.CreatableConnection).CreateDataCollectionLike(ctx, staging.Name, dDC.Name); err != nil {
+			return dDC, err
+		}
+		return staging, destConn.(driver.CopyableConnection).CopyDataCollection(ctx, dDC.Name, staging.Name)
+	default:
+		return staging, destConn.(driver.CreatableConnection).CreateDataCollectionLike(ctx, staging.Name, dDC.Name)
+	}
+}
+
+// expectedDataSetsOf returns the number of data sets of the source data collection in its read range.
+func (g *GLoader) expectedDataSetsOf(dc driver.DataCollectionDetail) uint64 {
+	startOffset, endOffset := g.readRangeOf(dc)
+	// the data sets after the count don't exist, even if the end offset is after it.
+	if count := uint64(dc.DataSetCount); endOffset > count {
+		endOffset = count
+	}
+	if startOffset >= endOffset {
+		return 0
+	}
+	return endOffset - startOffset
+}
+
+// verifyStaging returns an error if the staging data collection doesn't have as many data sets
+// as the source data collection has in the read range of this run,
+// plus the existing data sets of the destination if they're appended to.
+func (g *GLoader) verifyStaging(ctx context.Context, destConn driver.Connection, dc, dDC driver.DataCollectionDetail) error {
+	counter := destConn.(driver.CountableConnection)
+	staging := dDC.Name + stagingSuffix
+
+	expected := g.expectedDataSetsOf(dc)
+	if g.loadStrategyOf(dc.Name) == LoadAppend {
+		existing, err := counter.CountDataSets(ctx, dDC.Name)
+		if err != nil {
+			return err
+		}
+		expected += existing
+	}
+
+	count, err := counter.CountDataSets(ctx, staging)
+	if err != nil {
+		return err
+	}
+	if count != expected {
+		return fmt.Errorf("%w: %s has %d data sets, expected %d", ErrStagingVerificationFailed, staging, count, expected)
+	}
+	g.logger.WithPrefix(dc.Name).Info("verified the staging of the destination", "staging", staging, "data_sets", count)
+	return nil
+}
+
+// finishStaging verifies the staging data collection if it's enabled, and swaps it with the destination
+// data collection in a single transaction. The old destination data collection is dropped afterwards.
+// An error is returned only if the staging data collection isn't swapped in, so it can be dropped.
+func (g *GLoader) finishStaging(ctx context.Context, destConn driver.Connection, dc, dDC driver.DataCollectionDetail) error {
+	if g.stagingVerification {
+		if err := g.verifyStaging(ctx, destConn, dc, dDC); err != nil {
+			return err
+		}
+	}
+
+	staging := dDC.Name + stagingSuffix
+	old := dDC.Name + oldSuffix
+	g.logger.WithPrefix(dc.Name).Info("swapping the staging with the destination", "dest", dDC.Name, "staging", staging)
+	if err := destConn.(driver.DroppableConnection).DropDataCollection(ctx, old); err != nil {
+		return err
+	}
+	err := destConn.(driver.RenamableConnection).RenameDataCollections(
+		ctx,
+		// a recreated destination may not exist yet.
+		driver.Rename{From: dDC.Name, To: old, IfExists: true},
+		driver.Rename{From: staging, To: dDC.Name},
+	)
+	if err != nil {
+		return err
+	}
+	// the data collection is loaded once it's swapped in, and the old one is dropped by the next run anyway.
+	if err = destConn.(driver.DroppableConnection).DropDataCollection(ctx, old); err != nil {
+		g.logger.WithPrefix(dc.Name).Warn("failed to drop the old destination", "old", old, log.Err(err))
+	}
+	return nil
+}
+
+// abortStaging drops the staging data collection after the data collection failed to load,
+// and leaves the destination data collection untouched.
+func (g *GLoader) abortStaging(ctx context.Context, destConn driver.Connection, dc, dDC driver.DataCollectionDetail) {
+	staging := dDC.Name + stagingSuffix
+	// the context of the data collection may be canceled, but the staging should be dropped anyway.
+	err := destConn.(driver.DroppableConnection).DropDataCollection(context.WithoutCancel(ctx), staging)
+	if err != nil {
+		g.logger.WithPrefix(dc.Name).Warn("failed to drop the staging of the destination", "staging", staging, log.Err(err))
+	}
+}
@@ -0,0 +1,212 @@
+package gloader
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/pkg/log"
+)
+
+func TestStaging(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy LoadStrategy
+		// existing is the number of data sets of the destination, or -1 if it doesn't exist.
+		existing int
+		want     int
+	}{
+		{name: "swap", strategy: LoadSwap, existing: 5, want: 10},
+		{name: "append", strategy: LoadAppend, existing: 5, want: 15},
+		{name: "truncate", strategy: LoadTruncate, existing: 5, want: 10},
+		{name: "recreate", strategy: LoadRecreate, existing: 5, want: 10},
+		{name: "recreate a missing destination", strategy: LoadRecreate, existing: -1, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newMemoryDatabase(t, "src")
+			src.addDataCollection(t, "users", 10)
+			dest := newMemoryDatabase(t, "dest")
+			if tt.existing >= 0 {
+				dest.addDataCollection(t, "users", tt.existing)
+			}
+
+			g := newTestGLoader(t, src, dest).SetLoadStrategy(tt.strategy).SetStaging(true).SetStagingVerification(true)
+			if err := g.Start(); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(dest.ids("users")); got != tt.want {
+				t.Errorf("got %d data sets, want %d", got, tt.want)
+			}
+			// the staging and the old data collections are dropped.
+			if got, want := dest.names(), []string{"users"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("data collections = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFailedStagingLeavesTheDestinationUntouched(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 10)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 5)
+	errWrite := errors.New("write failed")
+	dest.writeErr = func(string, *data.Batch) error { return errWrite }
+
+	g := newTestGLoader(t, src, dest).SetLoadStrategy(LoadSwap)
+	if err := g.Start(); !errors.Is(err, errWrite) {
+		t.Fatalf("Start() error = %v, want %v", err, errWrite)
+	}
+	wantIDs(t, dest.ids("users"), 5)
+	if got, want := dest.names(), []string{"users"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("data collections = %v, want %v", got, want)
+	}
+}
+
+func TestStagingRefusesReferencedDataCollections(t *testing.T) {
+	for _, strategy := range []LoadStrategy{LoadAppend, LoadSwap} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			src := newMemoryDatabase(t, "src")
+			src.addDataCollection(t, "users", 10)
+			dest := newMemoryDatabase(t, "dest")
+			dest.addDataCollection(t, "users", 5)
+			dest.addDataCollection(t, "orders", 3, usersFK)
+
+			g := newTestGLoader(t, src, dest).SetLoadStrategy(strategy).SetStaging(true)
+			if err := g.Start(); !errors.Is(err, ErrReferencedDataCollection) {
+				t.Fatalf("Start() error = %v, want %v", err, ErrReferencedDataCollection)
+			}
+			wantIDs(t, dest.ids("users"), 5)
+			if got, want := dest.names(), []string{"orders", "users"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("data collections = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStagingRefusesDataCollectionsWithForeignKeys(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "orders", 10)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 5)
+	dest.addDataCollection(t, "orders", 3, usersFK)
+
+	g := newTestGLoader(t, src, dest).SetLoadStrategy(LoadSwap)
+	if err := g.Start(); !errors.Is(err, ErrStagingNotSupported) {
+		t.Fatalf("Start() error = %v, want %v", err, ErrStagingNotSupported)
+	}
+	wantIDs(t, dest.ids("orders"), 3)
+	if got, want := dest.names(), []string{"orders", "users"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("data collections = %v, want %v", got, want)
+	}
+}
+
+func TestFailedDropAfterTheSwapKeepsTheSwappedDataCollection(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 10)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 5)
+	errDrop := errors.New("drop failed")
+	var oldDrops int
+	dest.ddlErr = func(statement, dataCollection string) error {
+		// the old data collection is dropped before the swap and after it, which fails only once.
+		if statement == "drop" && dataCollection == "users"+oldSuffix {
+			oldDrops++
+			if oldDrops == 2 {
+				return errDrop
+			}
+		}
+		return nil
+	}
+
+	out := &logBuffer{}
+	g := newTestGLoader(t, src, dest).SetLogger(log.NewLogger(log.NewHandler(out, out))).SetLoadStrategy(LoadSwap)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	wantIDs(t, dest.ids("users"), 10)
+	wantIDs(t, dest.ids("users"+oldSuffix), 5)
+	if logs := out.String(); !strings.Contains(logs, "WARN [users] failed to drop the old destination") {
+		t.Errorf("logs don't contain the failed drop:\n%s", logs)
+	}
+
+	// the next run drops the old data collection before it swaps.
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	wantIDs(t, dest.ids("users"), 10)
+	if got, want := dest.names(), []string{"users"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("data collections = %v, want %v", got, want)
+	}
+}
+
+func TestStagingVerification(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 10)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 5)
+
+	g := newTestGLoader(t, src, dest).SetLoadStrategy(LoadSwap).SetStagingVerification(true)
+	// the rows of the previous runs aren't expected in the staging of the next ones.
+	for i := 0; i < 2; i++ {
+		if err := g.Start(); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+		wantIDs(t, dest.ids("users"), 10)
+	}
+
+	// a trigger which inserts another data set for each written batch.
+	dest.writeErr = func(dataCollection string, _ *data.Batch) error {
+		dest.mu.Lock()
+		defer dest.mu.Unlock()
+		dc := dest.dataCollections[dataCollection]
+		dc.sets = append(dc.sets, newIDSet(t, -1))
+		return nil
+	}
+	if err := g.Start(); !errors.Is(err, ErrStagingVerificationFailed) {
+		t.Fatalf("Start() error = %v, want %v", err, ErrStagingVerificationFailed)
+	}
+	wantIDs(t, dest.ids("users"), 10)
+	if got, want := dest.names(), []string{"users"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("data collections = %v, want %v", got, want)
+	}
+}
+
+func TestStagingVerificationExpectsTheSourceDataSets(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 10)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 5)
+
+	// only the data sets in the read range are expected.
+	g := newTestGLoader(t, src, dest).
+		SetLoadStrategy(LoadSwap).
+		SetStagingVerification(true).
+		SetStartOffset("users", 2).
+		SetEndOffset("users", 20)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(dest.ids("users")); got != 8 {
+		t.Fatalf("got %d data sets in users, want 8", got)
+	}
+
+	// a source which loses data sets while it's read, so the writer writes all the data sets it's given.
+	src.readErr = func(dataCollection string, _ uint64) error {
+		src.mu.Lock()
+		defer src.mu.Unlock()
+		dc := src.dataCollections[dataCollection]
+		dc.sets = dc.sets[:5]
+		return nil
+	}
+	g = newTestGLoader(t, src, dest).SetLoadStrategy(LoadSwap).SetStagingVerification(true).SetRowsPerBatch(100)
+	if err := g.Start(); !errors.Is(err, ErrStagingVerificationFailed) {
+		t.Fatalf("Start() error = %v, want %v", err, ErrStagingVerificationFailed)
+	}
+	if got := len(dest.ids("users")); got != 8 {
+		t.Errorf("got %d data sets in users, want the 8 of the previous run", got)
+	}
+}
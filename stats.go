@@ -1,7 +1,15 @@
 package gloader
 
 import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"time"
+
 	"github.com/mohammadv184/gloader/data"
+	gdriver "github.com/mohammadv184/gloader/driver"
 	"github.com/mohammadv184/gloader/pkg/stats"
 )
 
@@ -16,21 +24,80 @@ const (
 	MetricBufferLengthRows           MetricKey = "buffer.length.rows"
 	MetricBufferTotalWriteLengthRows MetricKey = "buffer.totalWriteLength.rows"
 	MetricBufferTotalReadLengthRows  MetricKey = "buffer.totalReadLength.rows"
+	MetricBufferSpillSizeBytes       MetricKey = "buffer.spill.size.bytes"
+	MetricBufferTotalSpilledBytes    MetricKey = "buffer.totalSpilled.bytes"
+	MetricReaderBatchSizeRows        MetricKey = "reader.batchSize.rows"
+	MetricWriterBatchSizeRows        MetricKey = "writer.batchSize.rows"
+	MetricReaderLatencySeconds       MetricKey = "reader.latency.seconds"
+	MetricReaderTotalReadRows        MetricKey = "reader.totalRead.rows"
+	MetricReaderTotalReadBytes       MetricKey = "reader.totalRead.bytes"
+	MetricReaderTotalReadBatches     MetricKey = "reader.totalRead.batches"
+	MetricReaderTotalRetries         MetricKey = "reader.totalRetries"
+	MetricReaderTotalReconnects      MetricKey = "reader.totalReconnects"
+	MetricWriterLatencySeconds       MetricKey = "writer.latency.seconds"
+	MetricWriterTotalWrittenRows     MetricKey = "writer.totalWritten.rows"
+	MetricWriterTotalWrittenBytes    MetricKey = "writer.totalWritten.bytes"
+	MetricWriterTotalWrittenBatches  MetricKey = "writer.totalWritten.batches"
+	MetricWriterTotalRetries         MetricKey = "writer.totalRetries"
 )
 
+// ErrorClass is the class of a read or write error.
+type ErrorClass string
+
+const (
+	ErrorClassConnection ErrorClass = "connection"
+	ErrorClassTimeout    ErrorClass = "timeout"
+	ErrorClassCanceled   ErrorClass = "canceled"
+	ErrorClassOther      ErrorClass = "other"
+)
+
+var errorClasses = []ErrorClass{ErrorClassConnection, ErrorClassTimeout, ErrorClassCanceled, ErrorClassOther}
+
+// GetErrorClass returns the class of the given error.
+func GetErrorClass(err error) ErrorClass {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, gdriver.ErrConnectionIsClosed),
+		errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.As(err, &netErr):
+		return ErrorClassConnection
+	default:
+		return ErrorClassOther
+	}
+}
+
+// MetricReaderTotalErrors returns the metric key of the total read errors of the given class.
+func MetricReaderTotalErrors(class ErrorClass) MetricKey {
+	return MetricKey("reader.totalErrors." + string(class))
+}
+
+// MetricWriterTotalErrors returns the metric key of the total write errors of the given class.
+func MetricWriterTotalErrors(class ErrorClass) MetricKey {
+	return MetricKey("writer.totalErrors." + string(class))
+}
+
 type BufferObserverAdapter struct {
 	s      *stats.Stats
 	dcName string
 }
 
-var _ data.BufferObserver = &BufferObserverAdapter{}
+var (
+	_ data.BufferObserver = &BufferObserverAdapter{}
+	_ data.SpillObserver  = &BufferObserverAdapter{}
+)
 
 func (b *BufferObserverAdapter) SizeChanged(size uint64) {
-	b.s.MustGetGaugeCounter(MetricBufferLengthRows.String()).(stats.GaugeCounter).Set(int64(size), b.dcName)
+	b.s.MustGetGaugeCounter(MetricBufferSizeBytes.String()).Set(int64(size), b.dcName)
 }
 
 func (b *BufferObserverAdapter) LengthChanged(l uint64) {
-	b.s.MustGetGaugeCounter(MetricBufferLengthRows.String()).(stats.GaugeCounter).Set(int64(l), b.dcName)
+	b.s.MustGetGaugeCounter(MetricBufferLengthRows.String()).Set(int64(l), b.dcName)
 }
 
 func (b *BufferObserverAdapter) Write(n int) {
@@ -41,9 +108,18 @@ func (b *BufferObserverAdapter) Read(n int) {
 	b.s.MustGetSequentialCounter(MetricBufferTotalReadLengthRows.String()).IncBy(int64(n), b.dcName)
 }
 
+func (b *BufferObserverAdapter) Spilled(bytes uint64) {
+	b.s.MustGetSequentialCounter(MetricBufferTotalSpilledBytes.String()).IncBy(int64(bytes), b.dcName)
+}
+
+func (b *BufferObserverAdapter) SpillSizeChanged(size uint64) {
+	b.s.MustGetGaugeCounter(MetricBufferSpillSizeBytes.String()).Set(int64(size), b.dcName)
+}
+
 func NewBufferObserverAdapter(s *stats.Stats, dcName string) *BufferObserverAdapter {
 	s.MustGetGaugeCounter(MetricBufferLengthRows.String()).Set(0, dcName)
 	s.MustGetGaugeCounter(MetricBufferSizeBytes.String()).Set(0, dcName)
+	s.MustGetGaugeCounter(MetricBufferSpillSizeBytes.String()).Set(0, dcName)
 
 	return &BufferObserverAdapter{
 		s:      s,
@@ -51,6 +127,88 @@ func NewBufferObserverAdapter(s *stats.Stats, dcName string) *BufferObserverAdap
 	}
 }
 
+type BatchSizeObserverAdapter struct {
+	s      *stats.Stats
+	metric MetricKey
+	dcName string
+}
+
+var _ BatchSizeObserver = &BatchSizeObserverAdapter{}
+
+func (b *BatchSizeObserverAdapter) BatchSizeChanged(rows uint64) {
+	b.s.MustGetGaugeCounter(b.metric.String()).Set(int64(rows), b.dcName)
+}
+
+func NewBatchSizeObserverAdapter(s *stats.Stats, metric MetricKey, dcName string) *BatchSizeObserverAdapter {
+	return &BatchSizeObserverAdapter{
+		s:      s,
+		metric: metric,
+		dcName: dcName,
+	}
+}
+
+type ReaderObserverAdapter struct {
+	s      *stats.Stats
+	dcName string
+}
+
+var _ ReaderObserver = &ReaderObserverAdapter{}
+
+func (r *ReaderObserverAdapter) BatchRead(rows, bytes uint64, latency time.Duration) {
+	r.s.MustGetHistogram(MetricReaderLatencySeconds.String()).ObserveDuration(latency, r.dcName)
+	r.s.MustGetSequentialCounter(MetricReaderTotalReadRows.String()).IncBy(int64(rows), r.dcName)
+	r.s.MustGetSequentialCounter(MetricReaderTotalReadBytes.String()).IncBy(int64(bytes), r.dcName)
+	r.s.MustGetSequentialCounter(MetricReaderTotalReadBatches.String()).Inc(r.dcName)
+}
+
+func (r *ReaderObserverAdapter) ReadFailed(err error) {
+	r.s.MustGetSequentialCounter(MetricReaderTotalErrors(GetErrorClass(err)).String()).Inc(r.dcName)
+}
+
+func (r *ReaderObserverAdapter) ReadRetried() {
+	r.s.MustGetSequentialCounter(MetricReaderTotalRetries.String()).Inc(r.dcName)
+}
+
+func (r *ReaderObserverAdapter) Reconnected() {
+	r.s.MustGetSequentialCounter(MetricReaderTotalReconnects.String()).Inc(r.dcName)
+}
+
+func NewReaderObserverAdapter(s *stats.Stats, dcName string) *ReaderObserverAdapter {
+	return &ReaderObserverAdapter{
+		s:      s,
+		dcName: dcName,
+	}
+}
+
+type WriterObserverAdapter struct {
+	s      *stats.Stats
+	dcName string
+}
+
+var _ WriterObserver = &WriterObserverAdapter{}
+
+func (w *WriterObserverAdapter) BatchWritten(rows, bytes uint64, latency time.Duration) {
+	w.s.MustGetHistogram(MetricWriterLatencySeconds.String()).ObserveDuration(latency, w.dcName)
+	w.s.MustGetSequentialCounter(MetricWriterTotalWrittenRows.String()).IncBy(int64(rows), w.dcName)
+	w.s.MustGetSequentialCounter(MetricWriterTotalWrittenBytes.String()).IncBy(int64(bytes), w.dcName)
+	w.s.MustGetSequentialCounter(MetricWriterTotalWrittenBatches.String()).Inc(w.dcName)
+}
+
+func (w *WriterObserverAdapter) WriteFailed(err error) {
+	w.s.MustGetSequentialCounter(MetricWriterTotalErrors(GetErrorClass(err)).String()).Inc(w.dcName)
+}
+
+func (w *WriterObserverAdapter) WriteRetried() {
+	w.s.MustGetSequentialCounter(MetricWriterTotalRetries.String()).Inc(w.dcName)
+}
+
+func NewWriterObserverAdapter(s *stats.Stats, dcName string) *WriterObserverAdapter {
+	return &WriterObserverAdapter{
+		s:      s,
+		dcName: dcName,
+	}
+}
+
 func NewStats() *stats.Stats {
 	s := stats.New()
 
@@ -61,7 +219,7 @@ func NewStats() *stats.Stats {
 
 	s.RegisterGaugeCounter(
 		MetricBufferLengthRows.String(),
-		"buffer size in rows",
+		"buffer length in rows",
 	)
 
 	s.RegisterSequentialCounter(
@@ -74,5 +232,92 @@ func NewStats() *stats.Stats {
 		"total buffer reads in rows",
 	)
 
+	s.RegisterGaugeCounter(
+		MetricBufferSpillSizeBytes.String(),
+		"size of the buffer data spilled to disk in bytes",
+	)
+
+	s.RegisterSequentialCounter(
+		MetricBufferTotalSpilledBytes.String(),
+		"total buffer data spilled to disk in bytes",
+	)
+
+	s.RegisterGaugeCounter(
+		MetricReaderBatchSizeRows.String(),
+		"adaptive reader batch size in rows",
+	)
+
+	s.RegisterGaugeCounter(
+		MetricWriterBatchSizeRows.String(),
+		"adaptive writer batch size in rows",
+	)
+
+	s.RegisterHistogram(
+		MetricReaderLatencySeconds.String(),
+		"reader batch read latency in seconds",
+	)
+
+	s.RegisterSequentialCounter(
+		MetricReaderTotalReadRows.String(),
+		"total rows read from the source",
+	)
+
+	s.RegisterSequentialCounter(
+		MetricReaderTotalReadBytes.String(),
+		"total bytes read from the source",
+	)
+
+	s.RegisterSequentialCounter(
+		MetricReaderTotalReadBatches.String(),
+		"total batches read from the source",
+	)
+
+	s.RegisterSequentialCounter(
+		MetricReaderTotalRetries.String(),
+		"total retried reads",
+	)
+
+	s.RegisterSequentialCounter(
+		MetricReaderTotalReconnects.String(),
+		"total reader reconnects to the source",
+	)
+
+	s.RegisterHistogram(
+		MetricWriterLatencySeconds.String(),
+		"writer batch write latency in seconds",
+	)
+
+	s.RegisterSequentialCounter(
+		MetricWriterTotalWrittenRows.String(),
+		"total rows written to the destination",
+	)
+
+	s.RegisterSequentialCounter(
+		MetricWriterTotalWrittenBytes.String(),
+		"total bytes written to the destination",
+	)
+
+	s.RegisterSequentialCounter(
+		MetricWriterTotalWrittenBatches.String(),
+		"total batches written to the destination",
+	)
+
+	s.RegisterSequentialCounter(
+		MetricWriterTotalRetries.String(),
+		"total retried writes",
+	)
+
+	for _, class := range errorClasses {
+		s.RegisterSequentialCounter(
+			MetricReaderTotalErrors(class).String(),
+			"total "+string(class)+" errors on reading from the source",
+		)
+
+		s.RegisterSequentialCounter(
+			MetricWriterTotalErrors(class).String(),
+			"total "+string(class)+" errors on writing to the destination",
+		)
+	}
+
 	return s
 }
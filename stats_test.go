@@ -0,0 +1,92 @@
+package gloader
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	gdriver "github.com/mohammadv184/gloader/driver"
+)
+
+func TestGetErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{err: context.Canceled, want: ErrorClassCanceled},
+		{err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: ErrorClassTimeout},
+		{err: &net.OpError{Op: "read", Err: timeoutError{}}, want: ErrorClassTimeout},
+		{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: ErrorClassConnection},
+		{err: gdriver.ErrConnectionIsClosed, want: ErrorClassConnection},
+		{err: driver.ErrBadConn, want: ErrorClassConnection},
+		{err: errors.New("duplicate key"), want: ErrorClassOther},
+	}
+	for _, tt := range tests {
+		if got := GetErrorClass(tt.err); got != tt.want {
+			t.Errorf("GetErrorClass(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestBufferObserverAdapterGauges(t *testing.T) {
+	s := NewStats()
+	b := NewBufferObserverAdapter(s, "users")
+	b.SizeChanged(4096)
+	b.LengthChanged(10)
+
+	if got := s.MustGetGaugeCounter(MetricBufferSizeBytes.String()).Value("users"); got != 4096 {
+		t.Errorf("%s = %d, want 4096", MetricBufferSizeBytes, got)
+	}
+	if got := s.MustGetGaugeCounter(MetricBufferLengthRows.String()).Value("users"); got != 10 {
+		t.Errorf("%s = %d, want 10", MetricBufferLengthRows, got)
+	}
+}
+
+func TestReaderAndWriterObserverAdapters(t *testing.T) {
+	s := NewStats()
+	r := NewReaderObserverAdapter(s, "users")
+	r.BatchRead(10, 100, 20*time.Millisecond)
+	r.ReadFailed(gdriver.ErrConnectionIsClosed)
+	r.ReadRetried()
+	r.Reconnected()
+	w := NewWriterObserverAdapter(s, "users")
+	w.BatchWritten(5, 50, 2*time.Second)
+	w.WriteFailed(context.DeadlineExceeded)
+	w.WriteRetried()
+
+	counters := map[MetricKey]int64{
+		MetricReaderTotalReadRows:                     10,
+		MetricReaderTotalReadBytes:                    100,
+		MetricReaderTotalReadBatches:                  1,
+		MetricReaderTotalErrors(ErrorClassConnection): 1,
+		MetricReaderTotalRetries:                      1,
+		MetricReaderTotalReconnects:                   1,
+		MetricWriterTotalWrittenRows:                  5,
+		MetricWriterTotalWrittenBytes:                 50,
+		MetricWriterTotalWrittenBatches:               1,
+		MetricWriterTotalErrors(ErrorClassTimeout):    1,
+		MetricWriterTotalErrors(ErrorClassOther):      0,
+		MetricWriterTotalRetries:                      1,
+	}
+	for metric, want := range counters {
+		if got := s.MustGetSequentialCounter(metric.String()).Value("users"); got != want {
+			t.Errorf("%s = %d, want %d", metric, got, want)
+		}
+	}
+	if got := s.MustGetHistogram(MetricReaderLatencySeconds.String()).Sum("users"); got != 0.02 {
+		t.Errorf("%s sum = %v, want 0.02", MetricReaderLatencySeconds, got)
+	}
+	if got := s.MustGetHistogram(MetricWriterLatencySeconds.String()).Value("users"); got != 1 {
+		t.Errorf("%s count = %d, want 1", MetricWriterLatencySeconds, got)
+	}
+}
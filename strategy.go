@@ -0,0 +1,203 @@
+package gloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mohammadv184/gloader/driver"
+)
+
+var (
+	ErrUnknownLoadStrategy      = errors.New("unknown load strategy")
+	ErrLoadStrategyNotSupported = errors.New("destination does not support the load strategy")
+	ErrReferencedDataCollection = errors.New("destination data collection is referenced by foreign keys")
+)
+
+// LoadStrategy determines what happens to the existing data of a destination data collection.
+type LoadStrategy uint8
+
+const (
+	// LoadAppend appends the data sets to the existing ones.
+	LoadAppend LoadStrategy = iota
+	// LoadTruncate deletes the existing data sets before loading.
+	LoadTruncate
+	// LoadRecreate drops the destination data collection and creates it again from the source schema before loading.
+	// The recreated data collection has only the columns of the source, without the keys and indexes of the old one.
+	// It can't be used for data collections referenced by the foreign keys of other ones.
+	LoadRecreate
+	// LoadSwap is LoadTruncate through a staging data collection,
+	// so the old data sets can be read until the new ones are loaded.
+	LoadSwap
+)
+
+var loadStrategyNames = map[LoadStrategy]string{
+	LoadAppend:   "append",
+	LoadTruncate: "truncate",
+	LoadRecreate: "recreate",
+	LoadSwap:     "swap",
+}
+
+// String returns the name of the load strategy.
+func (s LoadStrategy) String() string {
+	return loadStrategyNames[s]
+}
+
+// GetLoadStrategyFromString returns the load strategy from its name.
+func GetLoadStrategyFromString(strategy string) (LoadStrategy, error) {
+	for k, v := range loadStrategyNames {
+		if strings.EqualFold(v, strategy) {
+			return k, nil
+		}
+	}
+	return LoadAppend, fmt.Errorf("%w: %s", ErrUnknownLoadStrategy, strategy)
+}
+
+// SetLoadStrategy sets the load strategy of all the data collections. The default strategy is LoadAppend.
+func (g *GLoader) SetLoadStrategy(strategy LoadStrategy) *GLoader {
+	g.loadStrategy = strategy
+	return g
+}
+
+// SetDataCollectionLoadStrategy sets the load strategy of the data collection, instead of the global one.
+func (g *GLoader) SetDataCollectionLoadStrategy(dataCollection string, strategy LoadStrategy) *GLoader {
+	g.dataCollectionLoadStrategy[dataCollection] = strategy
+	return g
+}
+
+// loadStrategyOf returns the load strategy of the given data collection.
+func (g *GLoader) loadStrategyOf(dataCollection string) LoadStrategy {
+	if strategy, ok := g.dataCollectionLoadStrategy[dataCollection]; ok {
+		return strategy
+	}
+	return g.loadStrategy
+}
+
+// checkLoadStrategies returns an error if the destination doesn't support the load strategy
+// or the staging of a data collection.
+func (g *GLoader) checkLoadStrategies(dcs []driver.DataCollectionDetail, dDetails driver.DatabaseDetail, destConn driver.Connection) error {
+	for _, dc := range dcs {
+		strategy := g.loadStrategyOf(dc.Name)
+		var ok bool
+		switch strategy {
+		case LoadAppend, LoadSwap:
+			ok = true
+		case LoadTruncate:
+			_, ok = destConn.(driver.TruncatableConnection)
+		case LoadRecreate:
+			_, isDroppable := destConn.(driver.DroppableConnection)
+			_, isCreatable := destConn.(driver.CreatableConnection)
+			ok = isDroppable && isCreatable
+		default:
+			return fmt.Errorf("%w: %d", ErrUnknownLoadStrategy, strategy)
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s of %s", ErrLoadStrategyNotSupported, strategy, dc.Name)
+		}
+		if strategy == LoadRecreate {
+			if err := g.checkNotReferenced(dc, dDetails, "recreated"); err != nil {
+				return err
+			}
+		}
+		if strategy == LoadTruncate && !g.stagingOf(dc.Name) {
+			if err := g.checkNotReferenced(dc, dDetails, "truncated"); err != nil {
+				return err
+			}
+		}
+		if g.stagingOf(dc.Name) {
+			if err := g.checkNotReferenced(dc, dDetails, "staged"); err != nil {
+				return err
+			}
+			if err := g.checkStagingForeignKeys(dc, dDetails); err != nil {
+				return err
+			}
+			if err := g.checkStaging(dc, destConn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkNotReferenced returns an error if the destination data collection of the given one is referenced
+// by the foreign keys of other destination data collections, because it's dropped or truncated without CASCADE,
+// which would fail or drop their constraints. A staged data collection is dropped after it's swapped,
+// and its foreign keys follow it when it's renamed, so it can't be referenced either.
+func (g *GLoader) checkNotReferenced(dc driver.DataCollectionDetail, dDetails driver.DatabaseDetail, how string) error {
+	name := g.destDataCollectionOf(dc.Name)
+	var referencing []string
+	for _, dDC := range dDetails.DataCollections {
+		// the self-references are dropped with the data collection.
+		if dDC.Name == name {
+			continue
+		}
+		for _, fk := range dDC.ForeignKeys {
+			if fk.ReferencedDataCollection == name {
+				referencing = append(referencing, dDC.Name)
+				break
+			}
+		}
+	}
+	if len(referencing) > 0 {
+		return fmt.Errorf("%w: %s is referenced by %s, so it can't be %s", ErrReferencedDataCollection, name, strings.Join(referencing, ", "), how)
+	}
+	return nil
+}
+
+// destDataCollectionFor returns the details of the destination data collection of the given one.
+// The recreated data collections have the source schema, so they may not exist yet.
+func (g *GLoader) destDataCollectionFor(dDetails driver.DatabaseDetail, dc driver.DataCollectionDetail) (driver.DataCollectionDetail, error) {
+	name := g.destDataCollectionOf(dc.Name)
+	if g.loadStrategyOf(dc.Name) == LoadRecreate {
+		return driver.DataCollectionDetail{Name: name, DataMap: dc.DataMap}, nil
+	}
+	return dDetails.GetDataCollection(name)
+}
+
+// prepareDestDataCollection prepares the destination data collection for loading by its load strategy,
+// and returns the details of the data collection which is written.
+func (g *GLoader) prepareDestDataCollection(ctx context.Context, destConn driver.Connection, dc, dDC driver.DataCollectionDetail) (driver.DataCollectionDetail, error) {
+	if g.stagingOf(dc.Name) {
+		return g.prepareStaging(ctx, destConn, dc, dDC)
+	}
+
+	logger := g.logger.WithPrefix(dc.Name)
+	switch g.loadStrategyOf(dc.Name) {
+	case LoadTruncate:
+		logger.Info("truncating the destination", "dest", dDC.Name)
+		return dDC, destConn.(driver.TruncatableConnection).TruncateDataCollection(ctx, dDC.Name)
+	case LoadRecreate:
+		// the keys and indexes of the old destination aren't kept.
+		logger.Info("recreating the destination from the source schema", "dest", dDC.Name)
+		if err := destConn.(driver.DroppableConnection).DropDataCollection(ctx, dDC.Name); err != nil {
+			return dDC, err
+		}
+		return dDC, destConn.(driver.CreatableConnection).CreateDataCollection(ctx, dDC.Name, dc)
+	default:
+		return dDC, nil
+	}
+}
+
+// finishDestDataCollection finishes loading the destination data collection,
+// after all its data sets are written.
+func (g *GLoader) finishDestDataCollection(ctx context.Context, destConn driver.Connection, dc, dDC driver.DataCollectionDetail) error {
+	if !g.stagingOf(dc.Name) {
+		return nil
+	}
+	return g.finishStaging(ctx, destConn, dc, dDC)
+}
+
+// abortDestDataCollection cleans up after the destination data collection failed to load.
+func (g *GLoader) abortDestDataCollection(ctx context.Context, destConn driver.Connection, dc, dDC driver.DataCollectionDetail) {
+	if !g.stagingOf(dc.Name) {
+		return
+	}
+	g.abortStaging(ctx, destConn, dc, dDC)
+}
+
+// preparesDestDataCollection returns true if the destination data collection of the given one is prepared
+// and finished with its own connection, because it isn't simply appended to.
+func (g *GLoader) preparesDestDataCollection(dataCollection string) bool {
+	return g.loadStrategyOf(dataCollection) != LoadAppend || g.stagingOf(dataCollection)
+}
@@ -0,0 +1,118 @@
+package gloader
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mohammadv184/gloader/driver"
+)
+
+// usersFK is a foreign key of orders referencing users.
+var usersFK = driver.ForeignKey{Name: "orders_user_id_fkey", Keys: []string{"user_id"}, ReferencedDataCollection: "users", ReferencedKeys: []string{"id"}}
+
+func TestGetLoadStrategyFromString(t *testing.T) {
+	for strategy, name := range loadStrategyNames {
+		if got, err := GetLoadStrategyFromString(name); err != nil || got != strategy {
+			t.Errorf("GetLoadStrategyFromString(%q) = %v, %v, want %v", name, got, err, strategy)
+		}
+	}
+	if got, err := GetLoadStrategyFromString("SWAP"); err != nil || got != LoadSwap {
+		t.Errorf("GetLoadStrategyFromString(SWAP) = %v, %v, want %v", got, err, LoadSwap)
+	}
+	if _, err := GetLoadStrategyFromString("upsert"); !errors.Is(err, ErrUnknownLoadStrategy) {
+		t.Errorf("GetLoadStrategyFromString(upsert) error = %v, want %v", err, ErrUnknownLoadStrategy)
+	}
+}
+
+func TestLoadStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy LoadStrategy
+		// existing is the number of data sets of the destination, or -1 if it doesn't exist.
+		existing int
+		src      int
+		want     int
+	}{
+		{name: "append", strategy: LoadAppend, existing: 5, src: 10, want: 15},
+		{name: "truncate", strategy: LoadTruncate, existing: 5, src: 10, want: 10},
+		// an empty source replaces the destination, unless it's appended to.
+		{name: "truncate an empty source", strategy: LoadTruncate, existing: 5, src: 0, want: 0},
+		{name: "append an empty source", strategy: LoadAppend, existing: 5, src: 0, want: 5},
+		{name: "recreate", strategy: LoadRecreate, existing: 5, src: 10, want: 10},
+		{name: "recreate a missing destination", strategy: LoadRecreate, existing: -1, src: 10, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newMemoryDatabase(t, "src")
+			src.addDataCollection(t, "users", tt.src)
+			dest := newMemoryDatabase(t, "dest")
+			if tt.existing >= 0 {
+				dest.addDataCollection(t, "users", tt.existing)
+			}
+
+			g := newTestGLoader(t, src, dest).SetLoadStrategy(tt.strategy)
+			if err := g.Start(); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(dest.ids("users")); got != tt.want {
+				t.Errorf("got %d data sets, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataCollectionLoadStrategy(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	dest := newMemoryDatabase(t, "dest")
+	for _, name := range []string{"users", "logs"} {
+		src.addDataCollection(t, name, 10)
+		dest.addDataCollection(t, name, 5)
+	}
+
+	g := newTestGLoader(t, src, dest).SetLoadStrategy(LoadTruncate).SetDataCollectionLoadStrategy("logs", LoadAppend)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	wantIDs(t, dest.ids("users"), 10)
+	if got := len(dest.ids("logs")); got != 15 {
+		t.Errorf("got %d data sets in logs, want 15", got)
+	}
+}
+
+func TestRefusesReferencedDataCollections(t *testing.T) {
+	for _, strategy := range []LoadStrategy{LoadTruncate, LoadRecreate} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			src := newMemoryDatabase(t, "src")
+			src.addDataCollection(t, "users", 10)
+			dest := newMemoryDatabase(t, "dest")
+			dest.addDataCollection(t, "users", 5)
+			dest.addDataCollection(t, "orders", 3, usersFK)
+
+			g := newTestGLoader(t, src, dest).SetLoadStrategy(strategy)
+			if err := g.Start(); !errors.Is(err, ErrReferencedDataCollection) {
+				t.Fatalf("Start() error = %v, want %v", err, ErrReferencedDataCollection)
+			}
+			// nothing is truncated, dropped or loaded.
+			wantIDs(t, dest.ids("users"), 5)
+			if got, want := dest.names(), []string{"orders", "users"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("data collections = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRecreateSelfReferencedDataCollection(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 10)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 5, driver.ForeignKey{
+		Keys: []string{"referrer_id"}, ReferencedDataCollection: "users", ReferencedKeys: []string{"id"},
+	})
+
+	g := newTestGLoader(t, src, dest).SetLoadStrategy(LoadRecreate)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	wantIDs(t, dest.ids("users"), 10)
+}
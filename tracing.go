@@ -0,0 +1,29 @@
+package gloader
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/mohammadv184/gloader"
+
+// Span attribute keys of the loader, in addition to the driver.Attribute* keys.
+const (
+	AttributeSrcDriver       = attribute.Key("gloader.src.driver")
+	AttributeDestDriver      = attribute.Key("gloader.dest.driver")
+	AttributeDataCollections = attribute.Key("gloader.data_collections")
+	AttributeDataSetCount    = attribute.Key("gloader.data_set_count")
+	AttributeReaderWorkers   = attribute.Key("gloader.reader.workers")
+	AttributeWriterWorkers   = attribute.Key("gloader.writer.workers")
+)
+
+// startSpan starts a span as a child of the span in the given context,
+// using the tracer provider of the parent span.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).
+		TracerProvider().
+		Tracer(tracerName).
+		Start(ctx, name, trace.WithAttributes(attrs...))
+}
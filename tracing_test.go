@@ -0,0 +1,63 @@
+package gloader
+
+import (
+	"testing"
+
+	"github.com/mohammadv184/gloader/driver"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGLoaderSpans(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 50)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 0)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	g := newTestGLoader(t, src, dest).SetTracerProvider(tp).SetRowsPerBatch(10).SetWorkers(1)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = append(spans[s.Name()], s)
+	}
+
+	root := spans["GLoader.Start"]
+	if len(root) != 1 {
+		t.Fatalf("got %d GLoader.Start spans, want 1", len(root))
+	}
+	if got := root[0].Parent(); got.IsValid() {
+		t.Errorf("GLoader.Start has parent %v, want a root span", got)
+	}
+	for _, name := range []string{"GLoader.loadDataCollection", "Reader.RunWorker", "Reader.read", "Writer.RunWorker", "Writer.write"} {
+		if len(spans[name]) == 0 {
+			t.Errorf("no %s span", name)
+			continue
+		}
+		for _, s := range spans[name] {
+			if s.SpanContext().TraceID() != root[0].SpanContext().TraceID() {
+				t.Errorf("%s isn't in the trace of GLoader.Start", name)
+			}
+		}
+	}
+
+	// 50 data sets are read in batches of 10.
+	if got := len(spans["Reader.read"]); got != 5 {
+		t.Errorf("got %d Reader.read spans, want 5", got)
+	}
+	var rows int64
+	for _, s := range spans["Writer.write"] {
+		for _, attr := range s.Attributes() {
+			if attr.Key == driver.AttributeBatchRows {
+				rows += attr.Value.AsInt64()
+			}
+		}
+	}
+	if rows != 50 {
+		t.Errorf("Writer.write spans have %d batch rows in total, want 50", rows)
+	}
+}
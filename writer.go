@@ -3,19 +3,36 @@ package gloader
 import (
 	"context"
 	"errors"
-	"log"
 	"sync"
+	"time"
 
 	"github.com/mohammadv184/gloader/data"
 	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/log"
+	"github.com/mohammadv184/gloader/pkg/ratelimit"
 )
 
+// WriterObserver is an interface that can be implemented to observe the writer workers.
+type WriterObserver interface {
+	// BatchWritten is called when a batch is written to the destination.
+	BatchWritten(rows, bytes uint64, latency time.Duration)
+	// WriteFailed is called when writing a batch fails.
+	WriteFailed(err error)
+	// WriteRetried is called when a failed write is retried.
+	WriteRetried()
+}
+
 type Writer struct {
 	buffer         *data.Buffer
 	connectionP    *driver.ConnectionPool
 	dataCollection string
 	workers        uint
 	rowPerBatch    uint64
+	batchSize      *AdaptiveBatchSize
+	rowsLimiters   ratelimit.Limiters
+	observer       WriterObserver
+	afterWrite     func(batch *data.Batch)
+	logger         *log.Logger
 	ctx            context.Context
 }
 
@@ -26,6 +43,7 @@ func NewWriter(ctx context.Context, dataCollection string, buffer *data.Buffer,
 		dataCollection: dataCollection,
 		workers:        DefaultWorkers,
 		rowPerBatch:    DefaultRowsPerBatch,
+		logger:         log.Default(),
 		ctx:            ctx,
 	}
 }
@@ -34,10 +52,36 @@ func (w *Writer) SetWorkers(workers uint) {
 	w.workers = workers
 }
 
+// SetLogger sets the logger of the writer workers.
+func (w *Writer) SetLogger(logger *log.Logger) {
+	w.logger = logger
+}
+
 func (w *Writer) SetRowsPerBatch(rowsPerBatch uint64) {
 	w.rowPerBatch = rowsPerBatch
 }
 
+// SetAdaptiveBatchSize makes the workers tune the number of rows per batch at runtime,
+// instead of using the static rows per batch.
+func (w *Writer) SetAdaptiveBatchSize(batchSize *AdaptiveBatchSize) {
+	w.batchSize = batchSize
+}
+
+// SetRowsRateLimiters sets the rate limiters of the rows written per second.
+func (w *Writer) SetRowsRateLimiters(limiters ...*ratelimit.Limiter) {
+	w.rowsLimiters = limiters
+}
+
+// SetObserver sets the observer of the writer workers.
+func (w *Writer) SetObserver(observer WriterObserver) {
+	w.observer = observer
+}
+
+// SetAfterBatchWritten sets a function that is called by the workers after each batch is written.
+func (w *Writer) SetAfterBatchWritten(afterWrite func(batch *data.Batch)) {
+	w.afterWrite = afterWrite
+}
+
 func (w *Writer) Start() error {
 	if w.buffer == nil {
 		return ErrBufferNotSet
@@ -49,59 +93,152 @@ func (w *Writer) Start() error {
 	wg := &sync.WaitGroup{}
 	wg.Add(int(w.workers))
 
+	errs := make([]error, w.workers)
 	for i := uint(0); i < w.workers; i++ {
-		go func() {
+		go func(i uint) {
 			defer wg.Done()
-			w.RunWorker()
-		}()
+			errs[i] = w.RunWorker()
+		}(i)
 	}
 
 	wg.Wait()
-	return nil
+	return errors.Join(errs...)
 }
 
-func (w *Writer) RunWorker() {
+// RunWorker writes the batches of the buffer to the destination until the buffer is closed.
+// It returns the first error that stops the worker, unless the writer is stopped.
+func (w *Writer) RunWorker() error {
+	ctx, span := startSpan(w.ctx, "Writer.RunWorker", driver.AttributeDataCollection.String(w.dataCollection))
+	defer span.End()
+
 	conn, cIndex, err := w.connectionP.Connect(w.ctx)
 	if err != nil {
-		panic(err)
+		if w.ctx.Err() != nil {
+			// the writer is stopped before the worker is started.
+			return nil
+		}
+		return err
 	}
+	defer func() {
+		if err := w.connectionP.CloseConnection(cIndex); err != nil {
+			w.logger.Warn("failed to close the destination connection", log.Err(err))
+		}
+	}()
 	wConn := conn.(driver.WritableConnection)
 
-	batch := data.NewDataBatch()
 	for {
-		batch.Clear()
-
-		for i := uint64(0); i < w.rowPerBatch; i++ {
-			dSet, err := w.buffer.Read()
-			if err != nil {
-				if errors.Is(err, data.ErrBufferIsClosed) {
-					batch.Add(dSet)
-					break
-				}
-				panic(err)
-				return
+		rowPerBatch := w.rowPerBatch
+		if w.batchSize != nil {
+			rowPerBatch = w.batchSize.Current()
+		}
+
+		_, readSpan := startSpan(ctx, "Buffer.ReadBatch", driver.AttributeDataCollection.String(w.dataCollection))
+		batch, err := w.buffer.ReadBatch(rowPerBatch)
+		if err == nil {
+			readSpan.SetAttributes(driver.AttributeBatchRows.Int64(int64(batch.GetLength())))
+		}
+		readSpan.End()
+		if err != nil {
+			if !errors.Is(err, data.ErrBufferIsClosed) {
+				return err
 			}
 
-			// fmt.Println("LengthChanged: ", w.dataCollection, batch.GetLength())
-			batch.Add(dSet)
+			w.logger.Debug("buffer closed, stopping writer worker")
+			return nil
 		}
 
-		if batch.GetLength() > 0 {
-			//fmt.Println("Writing: ", w.dataCollection, batch.GetLength())
-			if err := wConn.Write(w.ctx, w.dataCollection, batch); err != nil {
-				log.Printf("error on writing data to %s: %s", w.dataCollection, err)
-				panic(err)
+		// the wait only fails when the context is done.
+		_ = w.rowsLimiters.WaitN(w.ctx, batch.GetLength())
+		if w.ctx.Err() != nil {
+			// the writer is stopped while waiting for the rate limit.
+			return nil
+		}
+
+		// the write may consume the batch while retrying it in chunks.
+		written := *batch
+
+		if err := w.write(ctx, wConn, batch); err != nil {
+			if w.ctx.Err() != nil {
+				// the writer is stopped, so the failed write is expected.
+				return nil
 			}
+			w.logger.Error("failed to write data batch", log.Err(err))
+			return err
 		}
 
-		if w.buffer.IsClosed() && w.buffer.IsEmpty() {
-			//log.Printf("%s Writer worker is closed", w.dataCollection)
-			err := w.connectionP.CloseConnection(cIndex)
-			if err != nil {
-				// TODO: logging system
-				//log.Println(err)
+		if w.afterWrite != nil {
+			w.afterWrite(&written)
+		}
+	}
+}
+
+// write writes the given batch to the connection.
+// In adaptive batch size mode, a failed write backs off the batch size,
+// and the batch is retried in chunks of the new batch size.
+func (w *Writer) write(ctx context.Context, wConn driver.WritableConnection, batch *data.Batch) error {
+	ctx, span := startSpan(
+		ctx,
+		"Writer.write",
+		driver.AttributeDataCollection.String(w.dataCollection),
+		driver.AttributeBatchRows.Int64(int64(batch.GetLength())),
+		driver.AttributeBatchBytes.Int64(int64(batch.GetSize())),
+	)
+
+	err := w.writeBatch(ctx, wConn, batch)
+	if err == nil || w.batchSize == nil {
+		driver.EndSpan(span, err)
+		return err
+	}
+
+	for retry := 0; retry < DefaultAdaptiveWriteRetries && w.ctx.Err() == nil; retry++ {
+		w.batchSize.Backoff()
+		rowPerBatch := w.batchSize.Current()
+		w.logger.Warn("failed to write data batch, retrying", "rows_per_batch", rowPerBatch, log.Err(err))
+		if w.observer != nil {
+			w.observer.WriteRetried()
+		}
+		span.RecordError(err)
+		span.SetAttributes(driver.AttributeRetryCount.Int(retry + 1))
+
+		for batch.GetLength() > 0 {
+			n := rowPerBatch
+			if n > batch.GetLength() {
+				n = batch.GetLength()
 			}
-			return
+			chunk := (*batch)[:n]
+
+			if err = w.writeBatch(ctx, wConn, &chunk); err != nil {
+				break
+			}
+			*batch = (*batch)[n:]
 		}
+
+		if err == nil {
+			break
+		}
+	}
+	driver.EndSpan(span, err)
+	return err
+}
+
+// writeBatch writes the given batch to the connection, and reports the result
+// to the adaptive batch size and the observer.
+func (w *Writer) writeBatch(ctx context.Context, wConn driver.WritableConnection, batch *data.Batch) error {
+	writeStart := time.Now()
+	err := wConn.Write(ctx, w.dataCollection, batch)
+	writeLatency := time.Since(writeStart)
+	if err != nil {
+		if w.observer != nil {
+			w.observer.WriteFailed(err)
+		}
+		return err
+	}
+
+	if w.batchSize != nil {
+		w.batchSize.Observe(batch.GetLength(), batch.GetSize(), writeLatency)
 	}
+	if w.observer != nil {
+		w.observer.BatchWritten(batch.GetLength(), batch.GetSize(), writeLatency)
+	}
+	return nil
 }
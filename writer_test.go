@@ -0,0 +1,97 @@
+package gloader
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mohammadv184/gloader/data"
+	"github.com/mohammadv184/gloader/driver"
+	"github.com/mohammadv184/gloader/pkg/ratelimit"
+)
+
+func TestWriterWritesWholeBatches(t *testing.T) {
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 0)
+
+	buffer := data.NewBuffer(context.Background())
+	batch := data.NewDataBatch()
+	for i := int64(0); i < 25; i++ {
+		batch.Add(newIDSet(t, i))
+	}
+	if err := buffer.WriteBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+	if err := buffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := driver.GetDriver("memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := NewWriter(context.Background(), "users", buffer, driver.NewConnectionPool(driver.NewConnector(d, dest.name)))
+	writer.SetWorkers(1)
+	writer.SetRowsPerBatch(10)
+	if err := writer.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := dest.writtenBatches("users"), []uint64{10, 10, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("written batches = %v, want %v", got, want)
+	}
+	wantIDs(t, dest.ids("users"), 25)
+}
+
+func TestWriterStopsWhileRateLimited(t *testing.T) {
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	buffer := data.NewBuffer(ctx)
+	for i := int64(0); i < 10; i++ {
+		if err := buffer.Write(newIDSet(t, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := driver.GetDriver("memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := NewWriter(ctx, "users", buffer, driver.NewConnectionPool(driver.NewConnector(d, dest.name)))
+	writer.SetWorkers(1)
+	// the batch of 10 rows waits for 9 seconds.
+	writer.SetRowsRateLimiters(ratelimit.New(1))
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	if err := writer.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if got := dest.ids("users"); len(got) != 0 {
+		t.Errorf("written ids = %v, want none after the writer is stopped", got)
+	}
+}
+
+func TestGLoaderMovesBatches(t *testing.T) {
+	src := newMemoryDatabase(t, "src")
+	src.addDataCollection(t, "users", 1000)
+	dest := newMemoryDatabase(t, "dest")
+	dest.addDataCollection(t, "users", 0)
+
+	g := newTestGLoader(t, src, dest).SetRowsPerBatch(100).SetWorkers(4)
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantIDs(t, dest.ids("users"), 1000)
+	for _, n := range dest.writtenBatches("users") {
+		if n == 0 || n > 100 {
+			t.Errorf("written batch of %d data sets, want 1 to 100", n)
+		}
+	}
+	if rows, _ := g.tableProgress("users"); rows != 1000 {
+		t.Errorf("written rows = %d, want 1000", rows)
+	}
+}